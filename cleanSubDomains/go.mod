@@ -3,11 +3,11 @@ module github.com/secinto/cleanSubDomains
 go 1.17
 
 require (
-	github.com/antchfx/xpath v1.2.0
 	github.com/sirupsen/logrus v1.8.1
+	golang.org/x/net v0.17.0
 )
 
 require (
-	github.com/thedevsaddam/gojsonq/v2 v2.5.2 // indirect
-	golang.org/x/sys v0.0.0-20211216021012-1d35b9e2eb4e // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 )
@@ -2,35 +2,456 @@ package main
 
 import (
 	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/secinto/cleanSubDomains/purify"
 	log "github.com/sirupsen/logrus"
-	"github.com/thedevsaddam/gojsonq/v2"
 )
 
 func main() {
 	log.SetFormatter(&log.JSONFormatter{})
 
+	var diffFile string
+	flag.StringVar(&diffFile, "diff", "", "Path and name of a previous httpx JSON output to compare the input with. Only the differences are reported")
+
+	var diffReportFile string
+	flag.StringVar(&diffReportFile, "dr", "", "Path and name of the JSON file to write the differences to (diff mode)")
+
+	var importFiles string
+	flag.StringVar(&importFiles, "s", "", "Comma separated outputs of subdomain enumerators and resolvers to import, optionally prefixed with the format (subfinder:, amass:, list:, crtsh:, dnsx:, massdns:)")
+
+	var inventoryFile string
+	flag.StringVar(&inventoryFile, "oi", "", "Path and name of the JSON file to write all imported hosts with their sources and httpx results to")
+
+	var addressFile string
+	flag.StringVar(&addressFile, "ar", "", "Path and name of the JSON file to write the imported hosts grouped by the addresses they resolve to")
+
+	var takeoverFile string
+	flag.StringVar(&takeoverFile, "tc", "", "Path and name of the JSON file to write the subdomain takeover candidates, with dangling CNAMEs to third-party services or responses matching a takeover fingerprint, to")
+
+	var fingerprintFile string
+	flag.StringVar(&fingerprintFile, "tf", "", "Path and name of an additional takeover fingerprint file (can-i-take-over-xyz fingerprints.json format), taking precedence over the built-in fingerprints")
+
+	var unansweredFile string
+	flag.StringVar(&unansweredFile, "on", "", "Path and name of the file to write hosts which resolve but never answered HTTP to")
+
+	var includeUnprobed bool
+	flag.BoolVar(&includeUnprobed, "up", false, "Also write imported hosts without httpx result to the output file")
+
+	var permutationFile string
+	flag.StringVar(&permutationFile, "perm", "", "Path and name of the file to write permutations of the kept hosts to, as candidates for the next round of brute forcing")
+
+	var vocabularyFile string
+	flag.StringVar(&vocabularyFile, "ow", "", "Path and name of the file to write the words mined from the labels of the kept hosts to")
+
+	var wordFile string
+	flag.StringVar(&wordFile, "pw", "", "Path and name of a file with additional words of the target used for permutations")
+
+	var permutationLimit int
+	flag.IntVar(&permutationLimit, "pl", 50000, "Maximum amount of permutations to generate, 0 for no limit")
+
+	var minedWords int
+	flag.IntVar(&minedWords, "pm", 100, "Maximum amount of the most frequent mined words used for permutations, 0 for no limit")
+
+	var printStatistics bool
+	flag.BoolVar(&printStatistics, "stats", false, "Print the domain tree and statistics of the kept hosts")
+
+	var htmlFile string
+	flag.StringVar(&htmlFile, "oh", "", "Path and name of the HTML file to write the domain tree and statistics of the kept hosts to")
+
+	var topCount int
+	flag.IntVar(&topCount, "top", 10, "Amount of top values shown in the statistics, 0 for all")
+
+	var certificateFile string
+	flag.StringVar(&certificateFile, "tr", "", "Path and name of the JSON file to write the records grouped by TLS certificate, with expired, self-signed and mismatched certificates flagged, to")
+
+	var sanFile string
+	flag.StringVar(&sanFile, "san", "", "Path and name of the file to write in-scope names of TLS certificates which are missing from the input to")
+
+	var faviconFile string
+	flag.StringVar(&faviconFile, "ft", "", "Path and name of an additional favicon hash table, taking precedence over the built-in table")
+
+	var includeProducts string
+	flag.StringVar(&includeProducts, "fp", "", "Comma separated products, technologies or favicon hashes which should be filtered for")
+
+	var excludeProducts string
+	flag.StringVar(&excludeProducts, "xp", "", "Comma separated products, technologies or favicon hashes which should be excluded")
+
+	var productFile string
+	flag.StringVar(&productFile, "op", "", "Path and name of the JSON file to write the hosts per product to")
+
+	var dedupeService bool
+	flag.BoolVar(&dedupeService, "svc", false, "Keep one canonical URL per service of a host, merging URLs which redirect to the same target or return the same response on another port or scheme")
+
+	var serviceFile string
+	flag.StringVar(&serviceFile, "sr", "", "Path and name of the JSON file to write the services with their canonical URL and alternate entry points to (-svc)")
+
 	var dupCount int
 	flag.IntVar(&dupCount, "dc", 10, "Duplicate count. Maximum amount of duplicates allowed per input")
 
 	var inFile string
-	flag.StringVar(&inFile, "i", "httpx_output.json", "Path and name of the input JSON file as created from (pd) httpx")
+	flag.StringVar(&inFile, "i", "httpx_output.json", "Path and name of the input JSON file as created from (pd) httpx (- for stdin, empty to only use imported hosts)")
 
 	var outFile string
 	flag.StringVar(&outFile, "o", "domains_purified.txt", "Path and name of the output file to write")
 
+	var urlFile string
+	flag.StringVar(&urlFile, "ou", "", "Path and name of the file to write the URLs of the kept records to")
+
+	var jsonLinesFile string
+	flag.StringVar(&jsonLinesFile, "oj", "", "Path and name of the file to write the original httpx JSON lines of the kept records to")
+
+	var csvFile string
+	flag.StringVar(&csvFile, "oc", "", "Path and name of the CSV file to write the kept records to")
+
+	var csvColumnList string
+	flag.StringVar(&csvColumnList, "cc", purify.DefaultColumns, "Comma separated columns of the CSV file")
+
+	var reportFile string
+	flag.StringVar(&reportFile, "or", "", "Path and name of the JSON report listing every duplicate group with the kept and the dropped records")
+
+	var policyDefinition string
+	flag.StringVar(&policyDefinition, "sel", "first", "Representative selection policy. Comma separated strategies ("+strings.Join(purify.StrategyNames(), ", ")+") with optional weights (:N), or a preset (first, preferred)")
+
+	var keyDefinition string
+	flag.StringVar(&keyDefinition, "k", "default", "Duplicate key. Comma separated httpx fields with optional tolerance (~N) and modifiers (:lower, :nohost, :trim), or a preset ("+strings.Join(purify.PresetNames(), ", ")+")")
+
 	var host string
-	flag.StringVar(&host, "h", "", "Host name or IP which should be filtered for")
+	flag.StringVar(&host, "h", "", "Host name or IP which should be filtered for. Comma separated host names, globs, regular expressions (re:), IPs or CIDR ranges")
+
+	var excludeHost string
+	flag.StringVar(&excludeHost, "x", "", "Host name or IP which should be excluded. Same format as -h")
+
+	var scopeFile string
+	flag.StringVar(&scopeFile, "scope", "", "Path and name of a file containing in-scope rules")
+
+	var outOfScopeFile string
+	flag.StringVar(&outOfScopeFile, "oos", "", "Path and name of a file containing out-of-scope rules")
+
+	var scopeReportFile string
+	flag.StringVar(&scopeReportFile, "fr", "", "Path and name of the JSON file to write the records removed by each scope rule to")
+
+	var threshold float64
+	flag.Float64Var(&threshold, "sim", 0, "Similarity threshold (0-1) for clustering similar responses using simhash, 0 disables clustering")
+
+	var clusterFile string
+	flag.StringVar(&clusterFile, "cr", "", "Path and name of the JSON file to write the cluster report to")
+
+	var providerFile string
+	flag.StringVar(&providerFile, "pt", "", "Path and name of an additional CDN, WAF and cloud provider table, taking precedence over the built-in table")
+
+	var providerMode string
+	flag.StringVar(&providerMode, "cdn", purify.ProviderTag, "Handling of hosts behind a CDN or WAF: tag (only tag the records), collapse (group them by provider, status and title) or separate (write them to -of)")
+
+	var frontedFile string
+	flag.StringVar(&frontedFile, "of", "", "Path and name of the output file to write the hosts behind a CDN or WAF to (-cdn separate)")
+
+	var wildcardChildren int
+	flag.IntVar(&wildcardChildren, "wc", 0, "Minimum amount of subdomains sharing addresses and response to detect a wildcard zone, 0 disables the detection")
+
+	var wildcardFile string
+	flag.StringVar(&wildcardFile, "wr", "", "Path and name of the JSON file to write the detected wildcard zones to")
 
 	flag.Parse()
 
-	parseJsonToWordList(inFile, outFile)
-}
+	if dupCount < 1 {
+		log.Fatalf("The duplicate count (-dc) must be at least 1, got %d", dupCount)
+	}
 
-func parseJsonToWordList(inputFile string, outputFile string) {
+	key, err := purify.ParseGroupKey(keyDefinition)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	jq := gojsonq.New().File(inputFile)
-	log.Debug(jq)
+	policy, err := purify.ParseSelectionPolicy(policyDefinition)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if providerMode != purify.ProviderTag && providerMode != purify.ProviderCollapse && providerMode != purify.ProviderSeparate {
+		log.Fatalf("Unknown CDN mode %q", providerMode)
+	}
+	if providerMode == purify.ProviderSeparate && frontedFile == "" {
+		log.Fatal("The output file for hosts behind a CDN or WAF (-of) is required to separate them")
+	}
+	if serviceFile != "" && !dedupeService {
+		log.Fatal("The service report (-sr) requires the service deduplication (-svc)")
+	}
+	providers := purify.NewProviderTable()
+	if providerFile != "" {
+		if err := providers.LoadFile(providerFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := providers.LoadBuiltin(); err != nil {
+		log.Fatal(err)
+	}
+
+	favicons := purify.FaviconTable{}
+	if faviconFile != "" {
+		if err := favicons.LoadFile(faviconFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := favicons.LoadBuiltin(); err != nil {
+		log.Fatal(err)
+	}
+
+	fingerprints := purify.NewFingerprintTable()
+	if fingerprintFile != "" {
+		if err := fingerprints.LoadFile(fingerprintFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := fingerprints.LoadBuiltin(); err != nil {
+		log.Fatal(err)
+	}
+
+	csvColumnNames, err := purify.ValidateColumns(csvColumnList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	targetScope := &purify.Scope{}
+	if err := targetScope.AddRules(host, "-h", false); err != nil {
+		log.Fatal(err)
+	}
+	if err := targetScope.AddRules(excludeHost, "-x", true); err != nil {
+		log.Fatal(err)
+	}
+	if scopeFile != "" {
+		if err := targetScope.AddRuleFile(scopeFile, false); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if outOfScopeFile != "" {
+		if err := targetScope.AddRuleFile(outOfScopeFile, true); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	records := readInput(inFile)
+	inputCount := len(records)
+	purify.TagProviders(records, providers)
+	purify.TagProducts(records, favicons)
+
+	hosts := purify.NewInventory()
+	if importFiles != "" {
+		if err := hosts.ImportFiles(importFiles); err != nil {
+			log.Fatal(err)
+		}
+	}
+	hosts.Join(records)
+	if !targetScope.Empty() {
+		hosts.Filter(targetScope)
+	}
+
+	if diffFile != "" {
+		oldRecords := readInput(diffFile)
+		if !targetScope.Empty() {
+			oldRecords, _ = purify.FilterScope(oldRecords, targetScope)
+			records, _ = purify.FilterScope(records, targetScope)
+		}
+		diff := purify.DiffRecords(oldRecords, records)
+		diff.Old = diffFile
+		diff.New = inFile
+		diff.WriteSummary(os.Stdout)
+		if diffReportFile != "" {
+			writeJSONFile(diff, diffReportFile)
+			log.Infof("Wrote differences to %s", diffReportFile)
+		}
+		return
+	}
+
+	var scopeReports []*purify.ScopeRuleReport
+	if !targetScope.Empty() {
+		records, scopeReports = purify.FilterScope(records, targetScope)
+	}
+	productFilter := purify.NewProductFilter(includeProducts, excludeProducts)
+	if !productFilter.Empty() {
+		records = productFilter.Filter(records)
+	}
+	scoped := records
+
+	var services []*purify.Service
+	if dedupeService {
+		records, services = purify.DedupeServices(records)
+	}
+
+	var zones []*purify.WildcardZone
+	if wildcardChildren > 0 {
+		records, zones = purify.DetectWildcards(records, wildcardChildren)
+	}
+
+	grouped := records
+	var fronted []*purify.Record
+	if providerMode == purify.ProviderCollapse {
+		fronted, grouped = purify.SplitFronted(records)
+	}
+	var grouper purify.Grouper = key
+	if threshold > 0 {
+		grouper = purify.SimilarityGrouper{Threshold: threshold, Key: key}
+	}
+	groups := grouper.Group(grouped)
+	if len(fronted) > 0 {
+		frontedKey, err := purify.ParseGroupKey("provider,status_code,title:lower")
+		if err != nil {
+			log.Fatal(err)
+		}
+		groups = append(groups, frontedKey.Group(fronted)...)
+	}
+	purify.LimitGroups(groups, dupCount, policy)
+
+	kept := purify.KeptRecords(records, groups)
+	hosts.MarkKept(kept)
+	output := kept
+	if includeUnprobed {
+		for _, host := range hosts.Unprobed() {
+			output = append(output, &purify.Record{Input: host})
+		}
+	}
+	if providerMode == purify.ProviderSeparate {
+		frontedKept, originKept := purify.SplitFronted(output)
+		writeRecords(purify.HostWriter{}, originKept, outFile, "hosts")
+		writeRecords(purify.HostWriter{}, frontedKept, frontedFile, "hosts")
+	} else {
+		writeRecords(purify.HostWriter{}, output, outFile, "hosts")
+	}
+	if urlFile != "" {
+		writeRecords(purify.URLWriter{}, kept, urlFile, "URLs")
+	}
+	if jsonLinesFile != "" {
+		writeRecords(purify.JSONLinesWriter{}, kept, jsonLinesFile, "httpx records")
+	}
+	if csvFile != "" {
+		writeRecords(purify.CSVWriter{Columns: csvColumnNames}, kept, csvFile, "records")
+	}
+	if clusterFile != "" {
+		clusters := purify.ClusterReports(groups)
+		writeJSONFile(clusters, clusterFile)
+		log.Infof("Wrote %d clusters to %s", len(clusters), clusterFile)
+	}
+	if wildcardFile != "" {
+		writeReport(zones, len(zones), "wildcard zones", wildcardFile)
+	}
+	if serviceFile != "" {
+		writeReport(services, len(services), "services", serviceFile)
+	}
+	if scopeReportFile != "" {
+		writeReport(scopeReports, len(scopeReports), "scope rules with their removed records", scopeReportFile)
+	}
+	if permutationFile != "" || vocabularyFile != "" {
+		var keptHosts []string
+		seen := make(map[string]bool)
+		for _, r := range output {
+			if host := purify.HostName(r); !seen[host] {
+				seen[host] = true
+				keptHosts = append(keptHosts, host)
+			}
+		}
+		mined := purify.MineVocabulary(keptHosts)
+		if vocabularyFile != "" {
+			words := mined.Words(0)
+			writeLines(words, vocabularyFile)
+			log.Infof("Wrote %d words to %s", len(words), vocabularyFile)
+		}
+		if permutationFile != "" {
+			words := mined.Words(minedWords)
+			if wordFile != "" {
+				additional, err := purify.ReadWords(wordFile)
+				if err != nil {
+					log.Fatal(err)
+				}
+				known := make(map[string]bool)
+				for _, word := range words {
+					known[word] = true
+				}
+				for _, word := range additional {
+					if !known[word] {
+						known[word] = true
+						words = append(words, word)
+					}
+				}
+			}
+			candidates := purify.Permute(keptHosts, words, permutationLimit)
+			writeLines(candidates, permutationFile)
+			log.Infof("Wrote %d permutations to %s", len(candidates), permutationFile)
+		}
+	}
+	if printStatistics || htmlFile != "" {
+		stats := purify.ComputeStatistics(output, topCount)
+		if printStatistics {
+			stats.WriteText(os.Stdout)
+		}
+		if htmlFile != "" {
+			writeFile(htmlFile, stats.WriteHTML)
+			log.Infof("Wrote statistics of %d hosts to %s", stats.Hosts, htmlFile)
+		}
+	}
+	if productFile != "" {
+		products := purify.GroupProducts(scoped)
+		writeReport(products, len(products), "products with their hosts", productFile)
+	}
+	if certificateFile != "" {
+		certificates := purify.GroupCertificates(scoped)
+		writeReport(certificates, len(certificates), "certificates", certificateFile)
+	}
+	if sanFile != "" {
+		candidates := purify.SANCandidates(scoped, hosts.Contains, targetScope)
+		writeLines(candidates, sanFile)
+		log.Infof("Wrote %d names of certificates missing from the input to %s", len(candidates), sanFile)
+	}
+	if addressFile != "" {
+		addressGroups := hosts.GroupByAddresses()
+		writeJSONFile(addressGroups, addressFile)
+		log.Infof("Wrote %d address groups to %s", len(addressGroups), addressFile)
+	}
+	if takeoverFile != "" {
+		candidates := purify.MergeTakeovers(fingerprints.CheckRecords(scoped, hosts.CNAMEs),
+			fingerprints.CheckHosts(hosts), hosts.DanglingCNAMEs(providers))
+		writeJSONFile(candidates, takeoverFile)
+		log.Infof("Wrote %d subdomain takeover candidates to %s", len(candidates), takeoverFile)
+	}
+	if unansweredFile != "" {
+		unanswered := hosts.Unanswered()
+		writeLines(unanswered, unansweredFile)
+		log.Infof("Wrote %d hosts without HTTP response to %s", len(unanswered), unansweredFile)
+	}
+	if inventoryFile != "" {
+		inventory := hosts.Hosts()
+		writeJSONFile(inventory, inventoryFile)
+		log.Infof("Wrote %d hosts to %s", len(inventory), inventoryFile)
+	}
+	if reportFile != "" {
+		writeJSONFile(purify.CreateReport(inputCount, kept, groups, zones, scopeReports), reportFile)
+		log.Infof("Wrote report to %s", reportFile)
+	}
+}
 
-	jq.From("hosts").Where("length")
+// readInput reads all records from the httpx JSON output. If the input file is
+// "-" the output is read from stdin, if it is empty no records are read.
+// Records of hosts which couldn't be probed are ignored.
+func readInput(inputFile string) []*purify.Record {
+	if inputFile == "" {
+		return nil
+	}
+	var reader io.Reader = os.Stdin
+	if inputFile != "-" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		reader = file
+	}
 
+	records, stats, err := purify.ReadAll(purify.NewReader(reader))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("Read %d records from %s (%d lines, %d malformed, %d failed)", len(records), inputFile,
+		stats.Lines, stats.Malformed, stats.Records-len(records))
+	return records
 }
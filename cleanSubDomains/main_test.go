@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/secinto/cleanSubDomains/purify"
+)
+
+func TestWriteReport(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		entries interface{}
+		count   int
+		want    string
+	}{
+		{"nil", []*purify.WildcardZone(nil), 0, "[]"},
+		{"empty", []purify.ProductHosts{}, 0, "[]"},
+		{"services", []*purify.Service{{Host: "a.example.com", Target: "https://a.example.com/",
+			Canonical: "https://a.example.com/"}}, 1,
+			`[
+  {
+    "host": "a.example.com",
+    "target": "https://a.example.com/",
+    "canonical": "https://a.example.com/"
+  }
+]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, tt.name+".json")
+			writeReport(tt.entries, tt.count, tt.name, file)
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "lines.txt")
+	writeLines([]string{"a.example.com", "b.example.com"}, file)
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.example.com\nb.example.com\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
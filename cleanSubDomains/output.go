@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/secinto/cleanSubDomains/purify"
+	log "github.com/sirupsen/logrus"
+)
+
+// writeFile creates the output file and writes its content with write.
+func writeFile(outputFile string, write func(w io.Writer) error) {
+	file, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeRecords writes the records to the output file using the writer.
+func writeRecords(writer purify.Writer, records []*purify.Record, outputFile string, name string) {
+	var written int
+	writeFile(outputFile, func(w io.Writer) error {
+		var err error
+		written, err = writer.Write(w, records)
+		return err
+	})
+	log.Infof("Wrote %d %s to %s", written, name, outputFile)
+}
+
+// writeLines writes the lines to the file.
+func writeLines(lines []string, outputFile string) {
+	writeFile(outputFile, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		for _, line := range lines {
+			fmt.Fprintln(bw, line)
+		}
+		return bw.Flush()
+	})
+}
+
+// writeJSONFile writes the value as indented JSON to the file.
+func writeJSONFile(value interface{}, outputFile string) {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputFile, content, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeReport writes the entries of a report as indented JSON array to the
+// report file and logs their number using the description. count must be the
+// number of entries. Without entries an empty array is written instead of
+// null, so consumers can always iterate over the report.
+func writeReport(entries interface{}, count int, description string, reportFile string) {
+	if count == 0 {
+		entries = []struct{}{}
+	}
+	writeJSONFile(entries, reportFile)
+	log.Infof("Wrote %d %s to %s", count, description, reportFile)
+}
@@ -0,0 +1,208 @@
+package purify
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Issues of certificates.
+const (
+	issueExpired    = "expired"
+	issueSelfSigned = "self-signed"
+	issueMismatched = "mismatched"
+)
+
+// CertificateHost is a record using a certificate.
+type CertificateHost struct {
+	URL        string `json:"url"`
+	Host       string `json:"host"`
+	Mismatched bool   `json:"mismatched,omitempty"`
+}
+
+// CertificateGroup contains all records using the same certificate. Hosts
+// sharing a certificate are often served by the same backend.
+type CertificateGroup struct {
+	Fingerprint string            `json:"fingerprint"`
+	Subject     string            `json:"subject"`
+	Issuer      string            `json:"issuer"`
+	NotAfter    string            `json:"not_after,omitempty"`
+	Names       []string          `json:"names,omitempty"`
+	Issues      []string          `json:"issues,omitempty"`
+	Hosts       []CertificateHost `json:"hosts"`
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of the certificate
+// of the record, empty if httpx didn't output it.
+func certificateFingerprint(r *Record) string {
+	if r.TLS == nil {
+		return ""
+	}
+	return strings.ToLower(r.TLS.FingerprintHash.SHA256)
+}
+
+func certificateIssuer(r *Record) string {
+	if r.TLS == nil {
+		return ""
+	}
+	return r.TLS.IssuerCN
+}
+
+// certificateNames returns the subject and the alternative names of the
+// certificate of the record.
+func certificateNames(r *Record) []string {
+	if r.TLS == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range append([]string{r.TLS.SubjectCN}, r.TLS.SubjectAN...) {
+		name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+		if name != "" {
+			names = appendUnique(names, name)
+		}
+	}
+	return names
+}
+
+// inCertificate checks if the host name of the record is contained in the
+// subject or the alternative names of its TLS certificate (httpx -tls-grab).
+func inCertificate(r *Record) bool {
+	host := HostName(r)
+	for _, name := range certificateNames(r) {
+		if name == host {
+			return true
+		}
+		if strings.HasPrefix(name, "*.") && strings.Count(host, ".") == strings.Count(name, ".") &&
+			strings.HasSuffix(host, name[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// expiryFormats are the layouts of the expiry date of a certificate, as
+// written by the httpx versions.
+var expiryFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseExpiry parses the expiry date of a certificate.
+func parseExpiry(notAfter string) (time.Time, error) {
+	var err error
+	for _, layout := range expiryFormats {
+		var t time.Time
+		if t, err = time.Parse(layout, notAfter); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// certificateIssues returns if the certificate of the record is expired or
+// self-signed, as reported by httpx or derived from the certificate data.
+func certificateIssues(r *Record, now time.Time) []string {
+	if r.TLS == nil {
+		return nil
+	}
+	var issues []string
+	expired := r.TLS.Expired
+	if r.TLS.NotAfter != "" {
+		if notAfter, err := parseExpiry(r.TLS.NotAfter); err != nil {
+			log.Warnf("Could not parse the expiry of the certificate of %s: %v", r.URL, err)
+		} else if notAfter.Before(now) {
+			expired = true
+		}
+	}
+	if expired {
+		issues = append(issues, issueExpired)
+	}
+	if r.TLS.SelfSigned || r.TLS.SubjectDN != "" && r.TLS.SubjectDN == r.TLS.IssuerDN {
+		issues = append(issues, issueSelfSigned)
+	}
+	return issues
+}
+
+// GroupCertificates groups the records by the fingerprint of their
+// certificate. Certificates without fingerprint are identified by subject,
+// alternative names and issuer. The groups are ordered by their size.
+func GroupCertificates(records []*Record) []*CertificateGroup {
+	var groups []*CertificateGroup
+	byKey := make(map[string]*CertificateGroup)
+	now := time.Now()
+
+	for _, r := range records {
+		if r.TLS == nil {
+			continue
+		}
+		key := certificateSummary(r)
+		g, ok := byKey[key]
+		if !ok {
+			g = &CertificateGroup{
+				Fingerprint: certificateFingerprint(r),
+				Subject:     r.TLS.SubjectCN,
+				Issuer:      r.TLS.IssuerCN,
+				NotAfter:    r.TLS.NotAfter,
+				Names:       certificateNames(r),
+				Issues:      certificateIssues(r, now),
+			}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		host := CertificateHost{URL: r.URL, Host: HostName(r), Mismatched: r.TLS.Mismatched || !inCertificate(r)}
+		if host.Mismatched {
+			g.Issues = appendUnique(g.Issues, issueMismatched)
+		}
+		g.Hosts = append(g.Hosts, host)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Hosts) > len(groups[j].Hosts)
+	})
+	for _, g := range groups {
+		if len(g.Issues) > 0 {
+			log.Warnf("Certificate %s of %d hosts is %s", g.Subject, len(g.Hosts), strings.Join(g.Issues, ", "))
+		}
+	}
+	return groups
+}
+
+// SANCandidates returns the names of the certificates which are in scope but
+// not known yet. Wildcard names are returned as their base domain. Without
+// include rules only names below the registrable domains of the records are
+// in scope, otherwise every shared certificate (e.g. of a CDN) would add
+// foreign domains.
+func SANCandidates(records []*Record, known func(host string) bool, s *Scope) []string {
+	var domains map[string]bool
+	if len(s.include) == 0 {
+		domains = make(map[string]bool)
+		for _, r := range records {
+			if domain := SplitHost(HostName(r)).Domain; domain != "" {
+				domains[domain] = true
+			}
+		}
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, r := range records {
+		for _, name := range certificateNames(r) {
+			host, err := NormalizeHost(name)
+			if err != nil || seen[host] || known(host) {
+				continue
+			}
+			seen[host] = true
+			if domains != nil && !domains[SplitHost(host).Domain] {
+				continue
+			}
+			if s.match(&Record{Input: host}) == nil {
+				candidates = append(candidates, host)
+			}
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,107 @@
+package purify
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func tlsRecord(url string, fingerprint string, names ...string) *Record {
+	r := &Record{URL: url, TLS: &TLSData{SubjectCN: names[0], SubjectAN: names[1:], IssuerCN: "R3"}}
+	r.TLS.FingerprintHash.SHA256 = fingerprint
+	return r
+}
+
+func TestInCertificate(t *testing.T) {
+	tests := []struct {
+		url   string
+		names []string
+		want  bool
+	}{
+		{"https://www.example.com", []string{"example.com", "WWW.example.com."}, true},
+		{"https://api.example.com", []string{"*.example.com"}, true},
+		{"https://v1.api.example.com", []string{"*.example.com"}, false},
+		{"https://example.com", []string{"*.example.com"}, false},
+		{"https://shop.example.org", []string{"example.com", "*.example.com"}, false},
+	}
+	for _, tt := range tests {
+		if got := inCertificate(tlsRecord(tt.url, "", tt.names...)); got != tt.want {
+			t.Errorf("inCertificate(%s, %q) = %v, want %v", tt.url, tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestCertificateIssues(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tls  TLSData
+		want []string
+	}{
+		{TLSData{NotAfter: "2024-09-01T00:00:00Z"}, nil},
+		{TLSData{NotAfter: "2024-03-01T00:00:00Z"}, []string{issueExpired}},
+		{TLSData{NotAfter: "2024-03-01 00:00:00 +0000 UTC"}, []string{issueExpired}},
+		{TLSData{NotAfter: "2024-03-01"}, []string{issueExpired}},
+		{TLSData{NotAfter: "1st of March"}, nil},
+		{TLSData{NotAfter: "1st of March", Expired: true}, []string{issueExpired}},
+		{TLSData{SubjectDN: "CN=router", IssuerDN: "CN=router"}, []string{issueSelfSigned}},
+		{TLSData{SelfSigned: true, Expired: true}, []string{issueExpired, issueSelfSigned}},
+	}
+	for _, tt := range tests {
+		tls := tt.tls
+		if got := certificateIssues(&Record{URL: "https://example.com", TLS: &tls}, now); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("certificateIssues(%+v) = %q, want %q", tt.tls, got, tt.want)
+		}
+	}
+}
+
+func TestGroupCertificates(t *testing.T) {
+	records := []*Record{
+		tlsRecord("https://shop.example.org", "bb", "shop.example.org"),
+		tlsRecord("https://www.example.com", "aa", "example.com", "*.example.com"),
+		{URL: "http://plain.example.com"},
+		tlsRecord("https://api.example.com", "aa", "example.com", "*.example.com"),
+		tlsRecord("https://legacy.example.net", "aa", "example.com", "*.example.com"),
+	}
+	groups := GroupCertificates(records)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	g := groups[0]
+	if g.Fingerprint != "aa" || len(g.Hosts) != 3 || !reflect.DeepEqual(g.Names, []string{"example.com", "*.example.com"}) {
+		t.Errorf("first group = %+v, want the 3 hosts of aa", g)
+	}
+	if !reflect.DeepEqual(g.Issues, []string{issueMismatched}) || !g.Hosts[2].Mismatched || g.Hosts[0].Mismatched {
+		t.Errorf("first group issues = %q, hosts = %+v, want legacy.example.net mismatched", g.Issues, g.Hosts)
+	}
+	if groups[1].Fingerprint != "bb" || len(groups[1].Issues) != 0 {
+		t.Errorf("second group = %+v, want bb without issues", groups[1])
+	}
+}
+
+func TestSANCandidates(t *testing.T) {
+	records := []*Record{
+		tlsRecord("https://www.example.com", "aa", "www.example.com", "api.example.com", "*.dev.example.com",
+			"www.example.com"),
+		tlsRecord("https://cdn.example.com", "bb", "sni.cdn-provider.net", "shop.customer.org", "intra.example.com"),
+	}
+	known := func(host string) bool { return host == "www.example.com" || host == "cdn.example.com" }
+
+	// Without include rules only names of example.com are candidates
+	s := &Scope{}
+	if err := s.AddRules("intra.example.com", "-x", true); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"api.example.com", "dev.example.com"}
+	if got := SANCandidates(records, known, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates without include rules = %q, want %q", got, want)
+	}
+
+	s = &Scope{}
+	if err := s.AddRules("example.com,customer.org", "-h", false); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"api.example.com", "dev.example.com", "shop.customer.org", "intra.example.com"}
+	if got := SANCandidates(records, known, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates with include rules = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+package purify
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var tokenRegex = regexp.MustCompile(`[\p{L}\p{N}_-]+`)
+
+// ClusterMember is a record of a cluster with its similarity to the
+// representative of the cluster.
+type ClusterMember struct {
+	URL        string  `json:"url"`
+	Similarity float64 `json:"similarity"`
+}
+
+// ClusterReport is the report of a single cluster as written to the cluster
+// report file.
+type ClusterReport struct {
+	Key            string          `json:"key"`
+	Representative string          `json:"representative"`
+	Members        []ClusterMember `json:"members"`
+}
+
+// SimilarityGrouper groups records with similar responses, see
+// clusterRecords.
+type SimilarityGrouper struct {
+	Threshold float64
+	Key       *GroupKey
+}
+
+func (s SimilarityGrouper) Group(records []*Record) []*Group {
+	return clusterRecords(records, s.Threshold, s.Key)
+}
+
+// clusterRecords groups records whose responses are similar. The fingerprint
+// of a response is a 64 bit simhash, either as calculated by httpx (-hash
+// simhash) or from the stored response (-sr). Records are added to the first
+// cluster with the same status code whose representative is at least as
+// similar as the threshold. Records without a fingerprint are grouped by the
+// duplicate key as usual.
+func clusterRecords(records []*Record, threshold float64, key *GroupKey) []*Group {
+	var groups []*Group
+	var withoutFingerprint []*Record
+	fingerprints := make(map[*Record]uint64)
+	similarities := make(map[*Record]float64)
+
+	for _, r := range records {
+		fingerprint, ok := responseFingerprint(r)
+		if !ok {
+			withoutFingerprint = append(withoutFingerprint, r)
+			continue
+		}
+		fingerprints[r] = fingerprint
+
+		var found *Group
+		for _, g := range groups {
+			representative := g.Records[0]
+			if representative.StatusCode != r.StatusCode {
+				continue
+			}
+			if sim := similarity(fingerprints[representative], fingerprint); sim >= threshold {
+				found = g
+				similarities[r] = sim
+				break
+			}
+		}
+		if found == nil {
+			found = &Group{
+				Key:          fmt.Sprintf("%d|simhash:%016x", r.StatusCode, fingerprint),
+				similarities: similarities,
+			}
+			groups = append(groups, found)
+		}
+		found.Records = append(found.Records, r)
+	}
+
+	if len(withoutFingerprint) > 0 {
+		log.Infof("%d records without simhash or stored response are grouped by %s",
+			len(withoutFingerprint), key.definition)
+		groups = append(groups, groupRecords(withoutFingerprint, key)...)
+	}
+	return groups
+}
+
+// ClusterReports returns the representative and the members of every cluster
+// with more than one record.
+func ClusterReports(groups []*Group) []ClusterReport {
+	reports := []ClusterReport{}
+	for _, g := range groups {
+		if len(g.Records) < 2 {
+			continue
+		}
+		representative := g.Records[0]
+		report := ClusterReport{Key: g.Key, Representative: representative.URL}
+		for _, r := range g.Records[1:] {
+			member := ClusterMember{URL: r.URL, Similarity: 1}
+			if sim, ok := g.similarities[r]; ok {
+				member.Similarity = sim
+			}
+			report.Members = append(report.Members, member)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// responseFingerprint returns the simhash of the response body. The simhash
+// calculated by httpx is preferred over the one calculated from the stored
+// response.
+func responseFingerprint(r *Record) (uint64, bool) {
+	if r.Hash.BodySimhash != "" {
+		if fingerprint, err := strconv.ParseUint(r.Hash.BodySimhash, 10, 64); err == nil {
+			return fingerprint, true
+		}
+		if fingerprint, err := strconv.ParseUint(r.Hash.BodySimhash, 16, 64); err == nil {
+			return fingerprint, true
+		}
+	}
+	if r.StoredResponsePath != "" {
+		content, err := os.ReadFile(r.StoredResponsePath)
+		if err != nil {
+			log.Warnf("Could not read stored response of %s: %v", r.URL, err)
+			return 0, false
+		}
+		return simhash(responseBody(content), HostName(r)), true
+	}
+	return 0, false
+}
+
+// responseBody returns the body of a stored httpx response. The stored
+// response might start with the request, therefore the headers of the last
+// HTTP response are skipped.
+func responseBody(content []byte) []byte {
+	start := bytes.LastIndex(content, []byte("\nHTTP/"))
+	if start < 0 {
+		if !bytes.HasPrefix(content, []byte("HTTP/")) {
+			return content
+		}
+		start = 0
+	}
+	rest := content[start:]
+	for _, separator := range [][]byte{[]byte("\r\n\r\n"), []byte("\n\n")} {
+		if end := bytes.Index(rest, separator); end >= 0 {
+			return rest[end+len(separator):]
+		}
+	}
+	return nil
+}
+
+// simhash calculates the 64 bit simhash of the tokens of the body. Tokens
+// which are part of the host name are ignored, because catch-all virtual
+// hosts often reflect the requested host name.
+func simhash(body []byte, host string) uint64 {
+	ignored := make(map[string]bool)
+	if host != "" {
+		ignored[host] = true
+		for _, label := range strings.Split(host, ".") {
+			ignored[label] = true
+		}
+	}
+
+	var weights [64]int
+	for _, token := range tokenRegex.FindAllString(strings.ToLower(string(body)), -1) {
+		if ignored[token] {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(token))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+// similarity returns the similarity of two simhashes between 0 and 1, based
+// on the number of differing bits.
+func similarity(a uint64, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
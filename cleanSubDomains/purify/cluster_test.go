@@ -0,0 +1,105 @@
+package purify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// page returns an HTML page with count words using the prefix, the host is
+// reflected in the title like catch-all virtual hosts do.
+func page(host string, prefix string, count int, extra string) string {
+	words := make([]string, count)
+	for i := range words {
+		words[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<html><title>%s</title><body>%s %s</body></html>",
+		host, strings.Join(words, " "), extra)
+}
+
+func TestSimhashSimilarity(t *testing.T) {
+	base := simhash(responseBody([]byte(page("a.example.com", "word", 200, ""))), "a.example.com")
+	near := simhash(responseBody([]byte(page("b.example.com", "word", 200, "session1234"))), "b.example.com")
+	distinct := simhash(responseBody([]byte(page("c.example.com", "other", 200, ""))), "c.example.com")
+
+	if sim := similarity(base, base); sim != 1 {
+		t.Errorf("similarity of identical bodies = %v, want 1", sim)
+	}
+	if sim := similarity(base, near); sim < 0.9 {
+		t.Errorf("similarity of near-identical bodies = %v, want at least 0.9", sim)
+	}
+	if sim := similarity(base, distinct); sim >= 0.9 {
+		t.Errorf("similarity of distinct bodies = %v, want below 0.9", sim)
+	}
+}
+
+func TestClusterRecords(t *testing.T) {
+	dir := t.TempDir()
+	record := func(host string, status int, body string) *Record {
+		path := filepath.Join(dir, host+".txt")
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return &Record{URL: "https://" + host, Input: host, StatusCode: status, StoredResponsePath: path}
+	}
+	records := []*Record{
+		record("a.example.com", 200, page("a.example.com", "word", 200, "")),
+		record("b.example.com", 200, page("b.example.com", "word", 200, "session1234")),
+		record("c.example.com", 200, page("c.example.com", "other", 200, "")),
+		record("d.example.com", 404, page("d.example.com", "word", 200, "")),
+		{URL: "https://e.example.com", Input: "e.example.com", StatusCode: 200, Title: "No fingerprint"},
+	}
+
+	key, err := ParseGroupKey("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := clusterRecords(records, 0.9, key)
+
+	var got []string
+	for _, g := range groups {
+		var hosts []string
+		for _, r := range g.Records {
+			hosts = append(hosts, r.Input)
+		}
+		got = append(got, strings.Join(hosts, ","))
+	}
+	want := []string{"a.example.com,b.example.com", "c.example.com", "d.example.com", "e.example.com"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("clusters = %q, want %q", got, want)
+	}
+
+	reports := ClusterReports(groups)
+	if len(reports) != 1 || reports[0].Representative != "https://a.example.com" {
+		t.Fatalf("reports = %+v, want one cluster of a.example.com", reports)
+	}
+	if sim := reports[0].Members[0].Similarity; sim < 0.9 || sim > 1 {
+		t.Errorf("similarity of b.example.com = %v, want between 0.9 and 1", sim)
+	}
+}
+
+func TestClusterRecordsThreshold(t *testing.T) {
+	// The httpx simhashes differ in 4 of 64 bits, a similarity of 0.9375
+	records := []*Record{
+		{URL: "https://a.example.com", StatusCode: 200, Hash: Hashes{BodySimhash: "f0f0f0f0f0f0f0f0"}},
+		{URL: "https://b.example.com", StatusCode: 200, Hash: Hashes{BodySimhash: "f0f0f0f0f0f0f0ff"}},
+	}
+	key, err := ParseGroupKey("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		threshold float64
+		groups    int
+	}{
+		{0.9, 1},
+		{0.9375, 1},
+		{0.95, 2},
+	} {
+		if got := len(clusterRecords(records, tt.threshold, key)); got != tt.groups {
+			t.Errorf("threshold %v: got %d groups, want %d", tt.threshold, got, tt.groups)
+		}
+	}
+}
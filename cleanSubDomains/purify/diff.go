@@ -0,0 +1,162 @@
+package purify
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldChange is a single field of a record which differs between two scans.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ChangedHost is a host which exists in both scans but whose URLs or
+// responses changed.
+type ChangedHost struct {
+	Host    string        `json:"host"`
+	URLs    []string      `json:"urls"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// DiffReport lists the hosts which differ between two httpx scans of the same
+// scope.
+type DiffReport struct {
+	Old       string        `json:"old"`
+	New       string        `json:"new"`
+	Added     []string      `json:"added"`
+	Removed   []string      `json:"removed"`
+	Changed   []ChangedHost `json:"changed"`
+	Unchanged int           `json:"unchanged"`
+}
+
+// diffFields are the fields compared between two scans.
+var diffFields = []struct {
+	name  string
+	value func(r *Record) string
+}{
+	{"urls", func(r *Record) string { return strings.ToLower(r.URL) }},
+	{"status_code", func(r *Record) string { return strconv.Itoa(r.StatusCode) }},
+	{"title", func(r *Record) string { return r.Title }},
+	{"tech", func(r *Record) string {
+		tech := append([]string(nil), r.Tech...)
+		sort.Strings(tech)
+		return strings.Join(tech, ";")
+	}},
+	{"content_length", func(r *Record) string { return strconv.Itoa(r.ContentLength) }},
+	{"certificate", certificateSummary},
+}
+
+// DiffRecords compares the records of an old and a new scan by their host
+// name. New hosts are added, hosts missing in the new scan are removed and
+// for all others the URLs (schemes and ports), status codes, titles,
+// technologies, content lengths and certificates of all records of the host
+// are compared.
+func DiffRecords(oldRecords []*Record, newRecords []*Record) *DiffReport {
+	report := &DiffReport{Added: []string{}, Removed: []string{}, Changed: []ChangedHost{}}
+	oldHosts, oldByHost := recordsByHost(oldRecords)
+	newHosts, newByHost := recordsByHost(newRecords)
+
+	for _, host := range newHosts {
+		records := newByHost[host]
+		old, ok := oldByHost[host]
+		if !ok {
+			report.Added = append(report.Added, host)
+			continue
+		}
+		changed := ChangedHost{Host: host}
+		for _, r := range records {
+			changed.URLs = append(changed.URLs, r.URL)
+		}
+		for _, field := range diffFields {
+			if o, n := hostValue(old, field.value), hostValue(records, field.value); o != n {
+				changed.Changes = append(changed.Changes, FieldChange{Field: field.name, Old: o, New: n})
+			}
+		}
+		if len(changed.Changes) > 0 {
+			report.Changed = append(report.Changed, changed)
+		} else {
+			report.Unchanged++
+		}
+	}
+	for _, host := range oldHosts {
+		if _, ok := newByHost[host]; !ok {
+			report.Removed = append(report.Removed, host)
+		}
+	}
+	return report
+}
+
+// recordsByHost groups the records by their host name. The hosts are returned
+// in the order they have been found.
+func recordsByHost(records []*Record) ([]string, map[string][]*Record) {
+	var hosts []string
+	byHost := make(map[string][]*Record)
+	for _, r := range records {
+		host := HostName(r)
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], r)
+	}
+	return hosts, byHost
+}
+
+// hostValue returns the sorted distinct values of the field of all records of
+// a host.
+func hostValue(records []*Record, value func(r *Record) string) string {
+	var values []string
+	for _, r := range records {
+		values = appendUnique(values, value(r))
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
+
+// certificateSummary describes the certificate of the record by its
+// fingerprint, or by subject, alternative names and issuer if httpx didn't
+// output the fingerprint.
+func certificateSummary(r *Record) string {
+	if r.TLS == nil {
+		return ""
+	}
+	if r.TLS.FingerprintHash.SHA256 != "" {
+		return "sha256:" + r.TLS.FingerprintHash.SHA256
+	}
+	names := append([]string(nil), r.TLS.SubjectAN...)
+	sort.Strings(names)
+	return fmt.Sprintf("cn=%s san=%s issuer=%s", r.TLS.SubjectCN, strings.Join(names, ";"), r.TLS.IssuerCN)
+}
+
+// WriteSummary writes a human-readable summary of the differences.
+func (d *DiffReport) WriteSummary(w io.Writer) {
+	fmt.Fprintf(w, "Comparing %s with %s\n", d.Old, d.New)
+	fmt.Fprintf(w, "%d new hosts, %d disappeared, %d changed, %d unchanged\n", len(d.Added), len(d.Removed),
+		len(d.Changed), d.Unchanged)
+
+	if len(d.Added) > 0 {
+		fmt.Fprintln(w, "\nNew:")
+		for _, host := range d.Added {
+			fmt.Fprintf(w, "  + %s\n", host)
+		}
+	}
+	if len(d.Removed) > 0 {
+		fmt.Fprintln(w, "\nDisappeared:")
+		for _, host := range d.Removed {
+			fmt.Fprintf(w, "  - %s\n", host)
+		}
+	}
+	if len(d.Changed) > 0 {
+		fmt.Fprintln(w, "\nChanged:")
+		for _, c := range d.Changed {
+			fmt.Fprintf(w, "  ~ %s\n", c.Host)
+			for _, change := range c.Changes {
+				fmt.Fprintf(w, "      %s: %q -> %q\n", change.Field, change.Old, change.New)
+			}
+		}
+	}
+}
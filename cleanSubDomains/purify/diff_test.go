@@ -0,0 +1,51 @@
+package purify
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRecords(t *testing.T) {
+	oldRecords := []*Record{
+		{URL: "http://www.example.com", Input: "www.example.com", StatusCode: 200, Title: "Home"},
+		{URL: "https://api.example.com", Input: "api.example.com", StatusCode: 200, Title: "API"},
+		{URL: "https://mail.example.com", Input: "mail.example.com", StatusCode: 200, Title: "Mail"},
+		{URL: "https://old.example.com", Input: "old.example.com", StatusCode: 200, Title: "Old"},
+	}
+	newRecords := []*Record{
+		{URL: "https://www.example.com", Input: "www.example.com", StatusCode: 200, Title: "Home"},
+		{URL: "https://api.example.com", Input: "api.example.com", StatusCode: 200, Title: "API"},
+		{URL: "https://api.example.com:8443", Input: "api.example.com", StatusCode: 200, Title: "API"},
+		{URL: "https://mail.example.com", Input: "mail.example.com", StatusCode: 200, Title: "Mail"},
+		{URL: "https://new.example.com", Input: "new.example.com", StatusCode: 200, Title: "New"},
+	}
+	diff := DiffRecords(oldRecords, newRecords)
+
+	if want := []string{"new.example.com"}; !reflect.DeepEqual(diff.Added, want) {
+		t.Errorf("added = %q, want %q", diff.Added, want)
+	}
+	if want := []string{"old.example.com"}; !reflect.DeepEqual(diff.Removed, want) {
+		t.Errorf("removed = %q, want %q", diff.Removed, want)
+	}
+	want := []ChangedHost{
+		{Host: "www.example.com", URLs: []string{"https://www.example.com"}, Changes: []FieldChange{
+			{Field: "urls", Old: "http://www.example.com", New: "https://www.example.com"}}},
+		{Host: "api.example.com", URLs: []string{"https://api.example.com", "https://api.example.com:8443"},
+			Changes: []FieldChange{{Field: "urls", Old: "https://api.example.com",
+				New: "https://api.example.com, https://api.example.com:8443"}}},
+	}
+	if !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("changed = %+v, want %+v", diff.Changed, want)
+	}
+	if diff.Unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", diff.Unchanged)
+	}
+
+	var summary bytes.Buffer
+	diff.WriteSummary(&summary)
+	if !strings.Contains(summary.String(), "1 new hosts, 1 disappeared, 2 changed, 1 unchanged") {
+		t.Errorf("unexpected summary:\n%s", summary.String())
+	}
+}
@@ -0,0 +1,273 @@
+package purify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxCNAMEs is the maximum length of a CNAME chain followed in the massdns
+// output.
+const maxCNAMEs = 10
+
+// resolution is the DNS resolution of a single name as reported by a
+// resolver.
+type resolution struct {
+	name      string
+	status    string
+	cnames    []string
+	addresses []string
+}
+
+// AddressGroup lists the hosts resolving to the same addresses, which often
+// share the same infrastructure.
+type AddressGroup struct {
+	Addresses []string `json:"addresses"`
+	Hosts     []string `json:"hosts"`
+}
+
+// resolve adds the resolution to the host. The DNS status of a successful
+// resolution takes precedence over failed ones of other resolvers.
+func (inv *Inventory) resolve(res resolution, source string) {
+	entry := inv.add(res.name, source, res.addresses...)
+	if entry == nil {
+		return
+	}
+	for _, cname := range res.cnames {
+		if cname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(cname)), "."); cname != "" {
+			entry.CNAMEs = appendUnique(entry.CNAMEs, cname)
+		}
+	}
+	if len(res.addresses) > 0 {
+		entry.Resolved = true
+	}
+	status := strings.ToUpper(res.status)
+	if status != "" && (entry.DNSStatus == "" || status == "NOERROR") {
+		entry.DNSStatus = status
+	}
+}
+
+// importDnsx imports the JSON lines of dnsx (-json). The status code is only
+// written with -rcode, names which didn't resolve are only written then.
+func (inv *Inventory) importDnsx(content []byte) error {
+	return eachJSONLine(content, func(line []byte) error {
+		var entry struct {
+			Host       string   `json:"host"`
+			A          []string `json:"a"`
+			AAAA       []string `json:"aaaa"`
+			CNAME      []string `json:"cname"`
+			StatusCode string   `json:"status_code"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		inv.resolve(resolution{
+			name:      entry.Host,
+			status:    entry.StatusCode,
+			cnames:    entry.CNAME,
+			addresses: append(entry.A, entry.AAAA...),
+		}, formatDnsx)
+		return nil
+	})
+}
+
+// importMassdns imports the ndjson (-o J) or the simple (-o S) output of
+// massdns.
+func (inv *Inventory) importMassdns(content []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		return inv.importMassdnsJSON(content)
+	}
+	return inv.importMassdnsSimple(content)
+}
+
+func (inv *Inventory) importMassdnsJSON(content []byte) error {
+	return eachJSONLine(content, func(line []byte) error {
+		var entry struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Data   struct {
+				Answers []struct {
+					Type string `json:"type"`
+					Data string `json:"data"`
+				} `json:"answers"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		res := resolution{name: entry.Name, status: entry.Status}
+		for _, answer := range entry.Data.Answers {
+			switch strings.ToUpper(answer.Type) {
+			case "CNAME":
+				res.cnames = append(res.cnames, answer.Data)
+			case "A", "AAAA":
+				res.addresses = append(res.addresses, answer.Data)
+			}
+		}
+		inv.resolve(res, formatMassdns)
+		return nil
+	})
+}
+
+// importMassdnsSimple imports the answers of the simple output, one record
+// per line. The output doesn't contain the queried names, so the owners of
+// the answers are used, without the CNAME targets of other registrable
+// domains. The status is unknown, only answers are written.
+func (inv *Inventory) importMassdnsSimple(content []byte) error {
+	var owners []string
+	cnameOf := make(map[string]string)
+	addresses := make(map[string][]string)
+	targets := make(map[string]string)
+
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	for number := 1; sc.Scan(); number++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		name, value, ok := parseMassdnsLine(line)
+		if !ok {
+			log.Warnf("Skipping malformed line %d: %q", number, line)
+			continue
+		}
+		if _, seen := cnameOf[name]; !seen && addresses[name] == nil {
+			owners = append(owners, name)
+		}
+		if value.recordType == "CNAME" {
+			cnameOf[name] = value.data
+			targets[value.data] = name
+		} else {
+			addresses[name] = appendUnique(addresses[name], value.data)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range owners {
+		if alias, ok := targets[name]; ok && SplitHost(alias).Domain != SplitHost(name).Domain {
+			continue
+		}
+		res := resolution{name: name, status: "NOERROR"}
+		target := name
+		for i := 0; i < maxCNAMEs; i++ {
+			next, ok := cnameOf[target]
+			if !ok {
+				break
+			}
+			res.cnames = append(res.cnames, next)
+			target = next
+		}
+		res.addresses = addresses[target]
+		inv.resolve(res, formatMassdns)
+	}
+	return nil
+}
+
+// massdnsRecord is a single answer of the massdns simple output.
+type massdnsRecord struct {
+	recordType string
+	data       string
+}
+
+// parseMassdnsLine parses a line of the massdns simple output, e.g.
+// "www.example.com. A 192.0.2.1". Only A, AAAA and CNAME records are
+// returned.
+func parseMassdnsLine(line string) (string, massdnsRecord, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return "", massdnsRecord{}, false
+	}
+	recordType := strings.ToUpper(fields[1])
+	if recordType != "A" && recordType != "AAAA" && recordType != "CNAME" {
+		return "", massdnsRecord{}, false
+	}
+	name := strings.TrimSuffix(strings.ToLower(fields[0]), ".")
+	data := strings.TrimSuffix(strings.ToLower(fields[2]), ".")
+	return name, massdnsRecord{recordType: recordType, data: data}, name != "" && data != ""
+}
+
+// GroupByAddresses groups the hosts by the set of addresses they resolve to.
+// The groups are ordered by their size.
+func (inv *Inventory) GroupByAddresses() []*AddressGroup {
+	groups := []*AddressGroup{}
+	byAddresses := make(map[string]*AddressGroup)
+	for _, entry := range inv.hosts {
+		if len(entry.Addresses) == 0 {
+			continue
+		}
+		addresses := append([]string(nil), entry.Addresses...)
+		sort.Strings(addresses)
+		key := strings.Join(addresses, ",")
+		g, ok := byAddresses[key]
+		if !ok {
+			g = &AddressGroup{Addresses: addresses}
+			byAddresses[key] = g
+			groups = append(groups, g)
+		}
+		g.Hosts = append(g.Hosts, entry.Host)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Hosts) > len(groups[j].Hosts)
+	})
+	return groups
+}
+
+// DanglingCNAMEs returns the hosts with a CNAME to a service of the provider
+// table whose resolution failed or ended without addresses. Those services
+// often allow anyone to claim the name the CNAME points at.
+func (inv *Inventory) DanglingCNAMEs(table *ProviderTable) []*TakeoverCandidate {
+	candidates := []*TakeoverCandidate{}
+	for _, entry := range inv.hosts {
+		if len(entry.CNAMEs) == 0 {
+			continue
+		}
+		var evidence []string
+		switch entry.DNSStatus {
+		case "NXDOMAIN", "SERVFAIL", "REFUSED":
+			evidence = append(evidence, fmt.Sprintf("DNS status %s", entry.DNSStatus))
+		}
+		if !entry.Resolved && len(entry.Addresses) == 0 {
+			evidence = append(evidence, "CNAME chain doesn't resolve to an address")
+		}
+		if len(evidence) == 0 {
+			continue
+		}
+		for _, cname := range entry.CNAMEs {
+			p := table.lookupCNAME(cname)
+			if p == nil {
+				continue
+			}
+			if !entry.Probed {
+				evidence = append(evidence, "no HTTP response")
+			}
+			candidates = append(candidates, &TakeoverCandidate{
+				Host:     entry.Host,
+				CNAME:    cname,
+				Service:  p.Name,
+				Evidence: evidence,
+			})
+			log.Warnf("Possible subdomain takeover of %s, CNAME %s at %s: %s", entry.Host, cname, p.Name,
+				strings.Join(evidence, ", "))
+			break
+		}
+	}
+	return candidates
+}
+
+// Unanswered returns the hosts which resolve to an address but never
+// answered HTTP.
+func (inv *Inventory) Unanswered() []string {
+	var hosts []string
+	for _, entry := range inv.hosts {
+		if entry.Resolved && !entry.Probed {
+			hosts = append(hosts, entry.Host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,146 @@
+package purify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	dnsxOutput = `{"host":"www.example.com","resolver":["1.1.1.1:53"],"a":["192.0.2.10"],"status_code":"NOERROR"}
+{"host":"blog.example.com","resolver":["1.1.1.1:53"],"cname":["example-blog.herokuapp.com"],"status_code":"NXDOMAIN"}
+{"host":"docs.example.com","resolver":["1.1.1.1:53"],"cname":["example.github.io"],"a":["185.199.108.153"]}
+{"host":"old.example.com","resolver":["1.1.1.1:53"],"cname":["old.corp.example.com"],"status_code":"NXDOMAIN"}`
+
+	massdnsSimple = `shop.example.com. CNAME shops.example-cdn.com.
+shops.example-cdn.com. A 198.51.100.7
+assets.example.com. CNAME example-assets.s3.amazonaws.com.
+app.example.com. CNAME lb.example.com.
+lb.example.com. A 192.0.2.20
+mail.example.com. A 192.0.2.10
+`
+
+	massdnsJSON = `{"name":"static.example.com.","type":"A","class":"IN","status":"NOERROR","data":{"answers":[{"ttl":300,"type":"CNAME","class":"IN","name":"static.example.com.","data":"example.azurewebsites.net."}]},"resolver":"8.8.8.8:53"}
+{"name":"vpn.example.com.","type":"A","class":"IN","status":"NOERROR","data":{"answers":[{"ttl":300,"type":"A","class":"IN","name":"vpn.example.com.","data":"192.0.2.10"}]},"resolver":"8.8.8.8:53"}
+{"name":"gone.example.com.","type":"A","class":"IN","status":"NXDOMAIN","data":{}}`
+)
+
+func TestDetectResolverFormat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{dnsxOutput, formatDnsx},
+		{massdnsSimple, formatMassdns},
+		{massdnsJSON, formatMassdns},
+		{`{"host":"www.example.com","input":"example.com","source":"crtsh"}`, formatSubfinder},
+		{`{"name":"www.example.com","domain":"example.com","addresses":[]}`, formatAmass},
+		{"www.example.com\napi.example.com\n", formatList},
+	}
+	for _, tt := range tests {
+		if got := detectFormat([]byte(tt.content)); got != tt.want {
+			t.Errorf("detectFormat(%.40q) = %s, want %s", tt.content, got, tt.want)
+		}
+	}
+}
+
+func importResolverOutputs(t *testing.T) *Inventory {
+	t.Helper()
+
+	inv := NewInventory()
+	if err := inv.importDnsx([]byte(dnsxOutput)); err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.importMassdns([]byte(massdnsSimple)); err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.importMassdns([]byte(massdnsJSON)); err != nil {
+		t.Fatal(err)
+	}
+	inv.Join([]*Record{{URL: "https://www.example.com", Input: "www.example.com", A: []string{"192.0.2.10"}}})
+	return inv
+}
+
+func TestImportResolverOutputs(t *testing.T) {
+	inv := importResolverOutputs(t)
+
+	var hosts []string
+	for _, entry := range inv.Hosts() {
+		hosts = append(hosts, entry.Host)
+	}
+	want := []string{"www.example.com", "blog.example.com", "docs.example.com", "old.example.com",
+		"shop.example.com", "assets.example.com", "app.example.com", "lb.example.com", "mail.example.com",
+		"static.example.com", "vpn.example.com", "gone.example.com"}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("hosts = %q, want %q", hosts, want)
+	}
+
+	tests := []struct {
+		host      string
+		cnames    []string
+		addresses []string
+		status    string
+		resolved  bool
+	}{
+		{"blog.example.com", []string{"example-blog.herokuapp.com"}, nil, "NXDOMAIN", false},
+		{"docs.example.com", []string{"example.github.io"}, []string{"185.199.108.153"}, "", true},
+		{"shop.example.com", []string{"shops.example-cdn.com"}, []string{"198.51.100.7"}, "NOERROR", true},
+		{"app.example.com", []string{"lb.example.com"}, []string{"192.0.2.20"}, "NOERROR", true},
+		{"static.example.com", []string{"example.azurewebsites.net"}, nil, "NOERROR", false},
+		{"gone.example.com", nil, nil, "NXDOMAIN", false},
+	}
+	for _, tt := range tests {
+		entry := inv.byHost[tt.host]
+		if entry == nil {
+			t.Errorf("%s not imported", tt.host)
+			continue
+		}
+		if !reflect.DeepEqual(entry.CNAMEs, tt.cnames) || !reflect.DeepEqual(entry.Addresses, tt.addresses) ||
+			entry.DNSStatus != tt.status || entry.Resolved != tt.resolved {
+			t.Errorf("%s = %v %v %q %v, want %v %v %q %v", tt.host, entry.CNAMEs, entry.Addresses,
+				entry.DNSStatus, entry.Resolved, tt.cnames, tt.addresses, tt.status, tt.resolved)
+		}
+	}
+}
+
+func TestGroupByAddresses(t *testing.T) {
+	groups := importResolverOutputs(t).GroupByAddresses()
+	if len(groups) != 4 {
+		t.Fatalf("got %d groups, want 4", len(groups))
+	}
+	want := AddressGroup{Addresses: []string{"192.0.2.10"},
+		Hosts: []string{"www.example.com", "mail.example.com", "vpn.example.com"}}
+	if !reflect.DeepEqual(*groups[0], want) {
+		t.Errorf("first group = %+v, want %+v", *groups[0], want)
+	}
+}
+
+func TestDanglingCNAMEs(t *testing.T) {
+	table := NewProviderTable()
+	if err := table.LoadBuiltin(); err != nil {
+		t.Fatal(err)
+	}
+	candidates := importResolverOutputs(t).DanglingCNAMEs(table)
+
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.Host+" "+c.Service+": "+strings.Join(c.Evidence, ", "))
+	}
+	want := []string{
+		"blog.example.com heroku: DNS status NXDOMAIN, CNAME chain doesn't resolve to an address, no HTTP response",
+		"assets.example.com aws: CNAME chain doesn't resolve to an address, no HTTP response",
+		"static.example.com azure: CNAME chain doesn't resolve to an address, no HTTP response",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates = %q, want %q", got, want)
+	}
+}
+
+func TestUnanswered(t *testing.T) {
+	got := importResolverOutputs(t).Unanswered()
+	want := []string{"docs.example.com", "shop.example.com", "app.example.com", "lb.example.com",
+		"mail.example.com", "vpn.example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unanswered() = %q, want %q", got, want)
+	}
+}
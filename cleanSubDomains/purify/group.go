@@ -0,0 +1,75 @@
+package purify
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Group contains all records with the same response. The best records up to
+// the allowed duplicate count are kept, the others are dropped.
+type Group struct {
+	Key     string
+	Records []*Record
+	Kept    []*Record
+	Dropped []*Record
+	// similarities of the records to the first record, if the group has
+	// been created by clustering similar responses
+	similarities map[*Record]float64
+	// selections are the scores of the records by the selection policy
+	selections map[*Record]Selection
+}
+
+// Group groups the records by the duplicate key.
+func (k *GroupKey) Group(records []*Record) []*Group {
+	return groupRecords(records, k)
+}
+
+// groupRecords groups records whose responses are duplicates of each other
+// according to the duplicate key.
+func groupRecords(records []*Record, key *GroupKey) []*Group {
+	var groups []*Group
+	byKey := make(map[string][]*Group)
+
+	for _, r := range records {
+		exact := key.exact(r)
+		var found *Group
+		for _, g := range byKey[exact] {
+			if key.within(g.Records[0], r) {
+				found = g
+				break
+			}
+		}
+		if found == nil {
+			found = &Group{Key: key.describe(r)}
+			byKey[exact] = append(byKey[exact], found)
+			groups = append(groups, found)
+		}
+		found.Records = append(found.Records, r)
+	}
+	return groups
+}
+
+// LimitGroups keeps at most dupCount records per group, chosen by the
+// selector, and drops the others. With a dupCount below 1 all records are
+// dropped.
+func LimitGroups(groups []*Group, dupCount int, selector Selector) {
+	for _, g := range groups {
+		g.Kept = nil
+		g.Dropped = nil
+		var ranked []*Record
+		ranked, g.selections = selector.Rank(g)
+		for _, r := range ranked {
+			if len(g.Kept) < dupCount {
+				g.Kept = append(g.Kept, r)
+			} else {
+				g.Dropped = append(g.Dropped, r)
+			}
+		}
+		switch {
+		case len(g.Dropped) == 0:
+		case len(g.Kept) == 0:
+			log.Infof("Dropped %d duplicates (%s)", len(g.Dropped), g.Key)
+		default:
+			log.Infof("Dropped %d duplicates of %s (%s)", len(g.Dropped), g.Kept[0].URL, g.Key)
+		}
+	}
+}
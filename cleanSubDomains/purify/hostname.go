@@ -0,0 +1,142 @@
+package purify
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// hostProfile maps international host names to punycode. Hyphens are not
+// checked, since names like r3---sn-abc are common, RFC 1123 is validated
+// separately.
+var hostProfile = idna.New(idna.MapForLookup(), idna.Transitional(false), idna.CheckHyphens(false), idna.BidiRule())
+
+// HostParts are the parts of a host name used for grouping. The suffix is the
+// public suffix (e.g. co.uk), the domain the registrable domain (e.g.
+// example.co.uk) and the subdomain the labels in front of it (e.g. api.dev).
+type HostParts struct {
+	Host      string `json:"host"`
+	Domain    string `json:"domain,omitempty"`
+	Subdomain string `json:"subdomain,omitempty"`
+	Suffix    string `json:"suffix,omitempty"`
+}
+
+// canonicalHost removes everything from the name which isn't part of the host
+// name: white space, scheme, user info, port, path, trailing dots and leading
+// wildcard labels. The result is in lower case.
+func canonicalHost(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, "://") {
+		if u, err := url.Parse(name); err == nil {
+			name = u.Host
+		}
+	} else if u, err := url.Parse("//" + name); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	if at := strings.LastIndex(name, "@"); at >= 0 {
+		name = name[at+1:]
+	}
+	if host, _, err := net.SplitHostPort(name); err == nil {
+		name = host
+	}
+	name = strings.Trim(name, "[]")
+	name = strings.TrimRight(strings.ToLower(name), ".")
+	for strings.HasPrefix(name, "*.") {
+		name = name[2:]
+	}
+	return name
+}
+
+// NormalizeHost returns the canonical host name with international names
+// encoded as punycode. An error is returned if the result isn't a valid host
+// name according to RFC 1123 and the public suffix list. IP addresses are
+// returned unchanged.
+func NormalizeHost(name string) (string, error) {
+	host := canonicalHost(name)
+	if host == "" {
+		return "", fmt.Errorf("empty host name")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	host, err := hostProfile.ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	if err := validateHost(host); err != nil {
+		return "", err
+	}
+	return host, nil
+}
+
+// validateHost checks that the host name conforms to RFC 1123 and that it has
+// a known public suffix and isn't a public suffix itself.
+func validateHost(host string) error {
+	if len(host) > 253 {
+		return fmt.Errorf("host name is longer than 253 characters")
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			return fmt.Errorf("empty label")
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("label %q is longer than 63 characters", label)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("label %q starts or ends with a hyphen", label)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Errorf("label %q contains invalid character %q", label, c)
+			}
+		}
+	}
+
+	suffix, icann := publicsuffix.PublicSuffix(host)
+	if !icann && !strings.Contains(suffix, ".") {
+		return fmt.Errorf("unknown top level domain %q", suffix)
+	}
+	if suffix == host {
+		return fmt.Errorf("host name is a public suffix")
+	}
+	return nil
+}
+
+// SplitHost splits a normalised host name into its registrable domain,
+// subdomain and public suffix. For IP addresses only the host is set.
+func SplitHost(host string) HostParts {
+	parts := HostParts{Host: host}
+	if net.ParseIP(host) != nil {
+		return parts
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return parts
+	}
+	parts.Domain = domain
+	parts.Suffix, _ = publicsuffix.PublicSuffix(host)
+	parts.Subdomain = strings.TrimSuffix(strings.TrimSuffix(host, domain), ".")
+	return parts
+}
+
+// HostName returns the normalised host name of the record without scheme and
+// port. If the host name isn't valid it is only converted to lower case.
+func HostName(r *Record) string {
+	if r.host != "" {
+		return r.host
+	}
+	name := r.URL
+	if canonicalHost(name) == "" {
+		name = r.Input
+	}
+	host, err := NormalizeHost(name)
+	if err != nil {
+		host = canonicalHost(name)
+	}
+	r.host = host
+	return host
+}
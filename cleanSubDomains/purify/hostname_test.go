@@ -0,0 +1,52 @@
+package purify
+
+import "testing"
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"www.example.com", "www.example.com", false},
+		{" WWW.Example.COM. ", "www.example.com", false},
+		{"https://user@api.example.com:8443/path", "api.example.com", false},
+		{"*.dev.example.com", "dev.example.com", false},
+		{"bücher.example.de", "xn--bcher-kva.example.de", false},
+		{"r3---sn-abc.example.com", "r3---sn-abc.example.com", false},
+		{"192.0.2.1", "192.0.2.1", false},
+		{"[2001:db8::1]:443", "2001:db8::1", false},
+		{"", "", true},
+		{"co.uk", "", true},
+		{"under_score.example.com", "", true},
+		{"-leading.example.com", "", true},
+		{"example.invalidtld", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeHost(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeHost(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeHost(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want HostParts
+	}{
+		{"api.dev.example.co.uk", HostParts{Host: "api.dev.example.co.uk", Domain: "example.co.uk",
+			Subdomain: "api.dev", Suffix: "co.uk"}},
+		{"example.com", HostParts{Host: "example.com", Domain: "example.com", Suffix: "com"}},
+		{"192.0.2.1", HostParts{Host: "192.0.2.1"}},
+	}
+	for _, tt := range tests {
+		if got := SplitHost(tt.host); got != tt.want {
+			t.Errorf("SplitHost(%q) = %+v, want %+v", tt.host, got, tt.want)
+		}
+	}
+}
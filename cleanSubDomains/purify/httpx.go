@@ -0,0 +1,237 @@
+package purify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Record is a single line of the (pd) httpx JSON output (-json). Fields which
+// are not known are ignored.
+type Record struct {
+	Timestamp     string   `json:"timestamp,omitempty"`
+	URL           string   `json:"url"`
+	Input         string   `json:"input"`
+	Host          string   `json:"host"`
+	Port          Port     `json:"port"`
+	Scheme        string   `json:"scheme"`
+	Path          string   `json:"path,omitempty"`
+	Method        string   `json:"method,omitempty"`
+	StatusCode    int      `json:"status_code"`
+	ContentLength int      `json:"content_length"`
+	ContentType   string   `json:"content_type,omitempty"`
+	Title         string   `json:"title"`
+	Webserver     string   `json:"webserver"`
+	Tech          []string `json:"tech,omitempty"`
+	Hash          Hashes   `json:"hash"`
+	A             []string `json:"a,omitempty"`
+	CNAME         []string `json:"cname,omitempty"`
+	CDN           bool     `json:"cdn"`
+	CDNName       string   `json:"cdn_name,omitempty"`
+	CDNType       string   `json:"cdn_type,omitempty"`
+	Words         int      `json:"words"`
+	Lines         int      `json:"lines"`
+	Failed        bool     `json:"failed"`
+	Favicon       string   `json:"favicon,omitempty"`
+	Location      string   `json:"location,omitempty"`
+	FinalURL      string   `json:"final_url,omitempty"`
+	TLS           *TLSData `json:"tls,omitempty"`
+
+	Body               string `json:"body,omitempty"`
+	BodyPreview        string `json:"body_preview,omitempty"`
+	StoredResponsePath string `json:"stored_response_path,omitempty"`
+
+	// raw is the original line, written unchanged to the JSON Lines output
+	raw []byte
+	// provider is the CDN, WAF or cloud provider the record is hosted at
+	provider *Provider
+	// host is the normalised host name, set by HostName
+	host string
+	// products are the products identified by favicon hash and technologies
+	products []string
+}
+
+// Hashes are the response hashes calculated by httpx (-hash).
+type Hashes struct {
+	BodyMD5       string `json:"body_md5,omitempty"`
+	BodyMMH3      string `json:"body_mmh3,omitempty"`
+	BodySHA256    string `json:"body_sha256,omitempty"`
+	BodySimhash   string `json:"body_simhash,omitempty"`
+	HeaderMD5     string `json:"header_md5,omitempty"`
+	HeaderMMH3    string `json:"header_mmh3,omitempty"`
+	HeaderSHA256  string `json:"header_sha256,omitempty"`
+	HeaderSimhash string `json:"header_simhash,omitempty"`
+}
+
+// TLSData is the TLS certificate information of httpx (-tls-grab).
+type TLSData struct {
+	SubjectDN  string   `json:"subject_dn,omitempty"`
+	SubjectCN  string   `json:"subject_cn,omitempty"`
+	SubjectAN  []string `json:"subject_an,omitempty"`
+	IssuerDN   string   `json:"issuer_dn,omitempty"`
+	IssuerCN   string   `json:"issuer_cn,omitempty"`
+	NotBefore  string   `json:"not_before,omitempty"`
+	NotAfter   string   `json:"not_after,omitempty"`
+	SelfSigned bool     `json:"self_signed,omitempty"`
+	Mismatched bool     `json:"mismatched,omitempty"`
+	Expired    bool     `json:"expired,omitempty"`
+
+	FingerprintHash struct {
+		SHA256 string `json:"sha256,omitempty"`
+	} `json:"fingerprint_hash"`
+}
+
+// recordFields are the fields of a record which can be written to the CSV
+// output and used for grouping, by their httpx JSON name.
+var recordFields = map[string]func(r *Record) string{
+	"url":            func(r *Record) string { return r.URL },
+	"input":          func(r *Record) string { return r.Input },
+	"host":           HostName,
+	"domain":         func(r *Record) string { return SplitHost(HostName(r)).Domain },
+	"subdomain":      func(r *Record) string { return SplitHost(HostName(r)).Subdomain },
+	"suffix":         func(r *Record) string { return SplitHost(HostName(r)).Suffix },
+	"ip":             func(r *Record) string { return r.Host },
+	"port":           func(r *Record) string { return string(r.Port) },
+	"scheme":         func(r *Record) string { return r.Scheme },
+	"path":           func(r *Record) string { return r.Path },
+	"method":         func(r *Record) string { return r.Method },
+	"status_code":    func(r *Record) string { return strconv.Itoa(r.StatusCode) },
+	"content_length": func(r *Record) string { return strconv.Itoa(r.ContentLength) },
+	"content_type":   func(r *Record) string { return r.ContentType },
+	"title":          func(r *Record) string { return r.Title },
+	"webserver":      func(r *Record) string { return r.Webserver },
+	"tech":           func(r *Record) string { return strings.Join(r.Tech, ";") },
+	"a":              func(r *Record) string { return strings.Join(r.A, ";") },
+	"cname":          func(r *Record) string { return strings.Join(r.CNAME, ";") },
+	"cdn":            func(r *Record) string { return strconv.FormatBool(r.CDN) },
+	"cdn_name":       func(r *Record) string { return r.CDNName },
+	"cdn_type":       func(r *Record) string { return r.CDNType },
+	"provider":       providerName,
+	"provider_kind":  providerKind,
+	"words":          func(r *Record) string { return strconv.Itoa(r.Words) },
+	"lines":          func(r *Record) string { return strconv.Itoa(r.Lines) },
+	"body_md5":       func(r *Record) string { return r.Hash.BodyMD5 },
+	"body_mmh3":      func(r *Record) string { return r.Hash.BodyMMH3 },
+	"body_sha256":    func(r *Record) string { return r.Hash.BodySHA256 },
+	"body_simhash":   func(r *Record) string { return r.Hash.BodySimhash },
+	"favicon":        func(r *Record) string { return r.Favicon },
+	"location":       func(r *Record) string { return r.Location },
+	"final_url":      func(r *Record) string { return r.FinalURL },
+	"tech_names":     func(r *Record) string { return strings.Join(techNames(r), ";") },
+	"product":        func(r *Record) string { return strings.Join(r.products, ";") },
+	"cert_sha256":    certificateFingerprint,
+	"cert_issuer":    certificateIssuer,
+	"timestamp":      func(r *Record) string { return r.Timestamp },
+}
+
+// fieldNames returns the sorted names of all record fields.
+func fieldNames() []string {
+	var names []string
+	for name := range recordFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Port is the port of the probed URL. Depending on the httpx version it is
+// written as string or as number.
+type Port string
+
+func (p *Port) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*p = Port(s)
+		return nil
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	n, err := strconv.Atoi(string(data))
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: "number " + string(data), Type: reflect.TypeOf(*p)}
+	}
+	*p = Port(strconv.Itoa(n))
+	return nil
+}
+
+// ReadStats are the statistics of reading a httpx JSON output.
+type ReadStats struct {
+	Lines     int
+	Records   int
+	Malformed int
+}
+
+// JSONLinesReader reads the httpx JSON Lines output (-json).
+type JSONLinesReader struct {
+	reader io.Reader
+}
+
+// NewReader returns a reader of the httpx JSON Lines output.
+func NewReader(reader io.Reader) *JSONLinesReader {
+	return &JSONLinesReader{reader: reader}
+}
+
+func (jr *JSONLinesReader) Read(fn func(r *Record)) (ReadStats, error) {
+	return readRecords(jr.reader, fn)
+}
+
+// readRecords reads the httpx JSON Lines output line by line and calls fn for
+// every record. Empty lines are skipped and lines which are no valid JSON are
+// logged and skipped as well.
+func readRecords(reader io.Reader, fn func(r *Record)) (ReadStats, error) {
+	var stats ReadStats
+	br := bufio.NewReaderSize(reader, 64*1024)
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			stats.Lines++
+			r, decodeErr := decodeRecord(line)
+			if decodeErr != nil {
+				stats.Malformed++
+				log.Warnf("Skipping malformed line %d: %v", stats.Lines, decodeErr)
+			} else if r != nil {
+				stats.Records++
+				fn(r)
+			}
+		}
+		if err == io.EOF {
+			return stats, nil
+		} else if err != nil {
+			return stats, err
+		}
+	}
+}
+
+// decodeRecord decodes a single line. If only single fields have an
+// unexpected type the record is used with those fields left empty. For empty
+// lines no record is returned.
+func decodeRecord(line []byte) (*Record, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil, nil
+	}
+
+	r := &Record{}
+	if err := json.Unmarshal(line, r); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return nil, err
+		}
+		log.Debugf("Unexpected field type: %v", err)
+	}
+	r.raw = append([]byte(nil), line...)
+	return r, nil
+}
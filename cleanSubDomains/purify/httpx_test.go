@@ -0,0 +1,85 @@
+package purify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadAll(t *testing.T) {
+	input := `{"url":"https://a.example.com","input":"a.example.com","port":"443","status_code":200}
+
+{"url":"https://b.example.com:8443","input":"b.example.com","port":8443,"status_code":200}
+not json
+{"url":"https://c.example.com","input":"c.example.com","failed":true}
+{"url":"https://d.example.com","input":"d.example.com","status_code":"200"}`
+
+	records, stats, err := ReadAll(NewReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Lines != 6 || stats.Records != 4 || stats.Malformed != 1 {
+		t.Errorf("stats = %+v, want 6 lines, 4 records, 1 malformed", stats)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	if records[0].Port != "443" || records[1].Port != "8443" {
+		t.Errorf("ports = %q, %q, want 443, 8443", records[0].Port, records[1].Port)
+	}
+	if records[2].URL != "https://d.example.com" || records[2].StatusCode != 0 {
+		t.Errorf("record with unexpected field type = %+v, want URL and no status code", records[2])
+	}
+}
+
+func TestWriters(t *testing.T) {
+	input := `{"url":"https://a.example.com","input":"a.example.com","status_code":200,"title":"A","tech":["Nginx","PHP"]}
+{"url":"http://a.example.com","input":"a.example.com","status_code":301,"title":""}
+{"url":"https://B.example.com","input":"b.example.com","status_code":200,"title":"B, \"quoted\""}`
+	records, _, err := ReadAll(NewReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		writer  Writer
+		want    string
+		written int
+	}{
+		{"hosts", HostWriter{}, "a.example.com\nb.example.com\n", 2},
+		{"urls", URLWriter{}, "https://a.example.com\nhttp://a.example.com\nhttps://B.example.com\n", 3},
+		{"jsonl", JSONLinesWriter{}, input + "\n", 3},
+		{"csv", CSVWriter{Columns: []string{"host", "status_code", "title", "tech"}},
+			"host,status_code,title,tech\na.example.com,200,A,Nginx;PHP\na.example.com,301,,\n" +
+				"b.example.com,200,\"B, \"\"quoted\"\"\",\n", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			written, err := tt.writer.Write(&buf, records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written != tt.written {
+				t.Errorf("wrote %d entries, want %d", written, tt.written)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateColumns(t *testing.T) {
+	columns, err := ValidateColumns(" url, ,status_code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(columns, ",") != "url,status_code" {
+		t.Errorf("columns = %q, want url,status_code", columns)
+	}
+	if _, err := ValidateColumns("url,nope"); err == nil {
+		t.Error("unknown column returned no error")
+	}
+}
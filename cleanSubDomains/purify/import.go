@@ -0,0 +1,344 @@
+package purify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Formats of the enumerator outputs which can be imported.
+const (
+	formatSubfinder = "subfinder"
+	formatAmass     = "amass"
+	formatList      = "list"
+	formatCrtsh     = "crtsh"
+	formatDnsx      = "dnsx"
+	formatMassdns   = "massdns"
+)
+
+// HostEntry is a host name found by one or more enumerators, joined with the
+// httpx records of the host.
+type HostEntry struct {
+	HostParts
+	Sources   []string `json:"sources"`
+	Addresses []string `json:"addresses,omitempty"`
+	CNAMEs    []string `json:"cnames,omitempty"`
+	DNSStatus string   `json:"dns_status,omitempty"`
+	Resolved  bool     `json:"resolved,omitempty"`
+	URLs      []string `json:"urls,omitempty"`
+	Probed    bool     `json:"probed"`
+	Kept      bool     `json:"kept"`
+}
+
+// Inventory contains all hosts merged by their normalised host name in the
+// order they have been found.
+type Inventory struct {
+	hosts    []*HostEntry
+	byHost   map[string]*HostEntry
+	rejected int
+}
+
+// NewInventory returns an empty inventory.
+func NewInventory() *Inventory {
+	return &Inventory{byHost: make(map[string]*HostEntry)}
+}
+
+// add adds the host found by the source. Host names which can't be
+// normalised are logged and skipped.
+func (inv *Inventory) add(name string, source string, addresses ...string) *HostEntry {
+	host, err := NormalizeHost(name)
+	if err != nil {
+		log.Debugf("Skipping host %q from %s: %v", name, source, err)
+		inv.rejected++
+		return nil
+	}
+	entry, ok := inv.byHost[host]
+	if !ok {
+		entry = &HostEntry{HostParts: SplitHost(host)}
+		inv.byHost[host] = entry
+		inv.hosts = append(inv.hosts, entry)
+	}
+	entry.Sources = appendUnique(entry.Sources, source)
+	for _, address := range addresses {
+		entry.Addresses = appendUnique(entry.Addresses, address)
+	}
+	return entry
+}
+
+// Join adds the httpx records to the hosts, hosts only found by httpx are
+// added as well.
+func (inv *Inventory) Join(records []*Record) {
+	for _, r := range records {
+		entry := inv.add(HostName(r), "httpx", r.A...)
+		if entry == nil {
+			continue
+		}
+		entry.Probed = true
+		entry.URLs = appendUnique(entry.URLs, r.URL)
+	}
+}
+
+// Filter removes all hosts which are not in scope.
+func (inv *Inventory) Filter(s *Scope) {
+	var hosts []*HostEntry
+	for _, entry := range inv.hosts {
+		if s.match(&Record{Input: entry.Host, A: entry.Addresses}) == nil {
+			hosts = append(hosts, entry)
+		} else {
+			delete(inv.byHost, entry.Host)
+		}
+	}
+	inv.hosts = hosts
+}
+
+// MarkKept marks the hosts of which at least one record has been kept.
+func (inv *Inventory) MarkKept(kept []*Record) {
+	for _, r := range kept {
+		if entry, ok := inv.byHost[HostName(r)]; ok {
+			entry.Kept = true
+		}
+	}
+}
+
+// Unprobed returns the hosts without httpx result.
+func (inv *Inventory) Unprobed() []string {
+	var hosts []string
+	for _, entry := range inv.hosts {
+		if !entry.Probed {
+			hosts = append(hosts, entry.Host)
+		}
+	}
+	return hosts
+}
+
+// ImportFiles imports the comma separated enumerator outputs. Each file can
+// be prefixed with its format (e.g. amass:amass.json), otherwise the format is
+// detected from the content.
+func (inv *Inventory) ImportFiles(files string) error {
+	for _, item := range strings.Split(files, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		format := ""
+		if colon := strings.Index(item, ":"); colon > 0 {
+			switch item[:colon] {
+			case formatSubfinder, formatAmass, formatList, formatCrtsh, formatDnsx, formatMassdns:
+				format = item[:colon]
+				item = item[colon+1:]
+			}
+		}
+		if err := inv.importFile(item, format); err != nil {
+			return fmt.Errorf("could not import %s: %v", item, err)
+		}
+	}
+	return nil
+}
+
+func (inv *Inventory) importFile(file string, format string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if format == "" {
+		format = detectFormat(content)
+	}
+
+	before := len(inv.hosts)
+	rejected := inv.rejected
+	switch format {
+	case formatSubfinder:
+		err = inv.importSubfinder(content)
+	case formatAmass:
+		err = inv.importAmass(content)
+	case formatCrtsh:
+		err = inv.importCrtsh(content)
+	case formatDnsx:
+		err = inv.importDnsx(content)
+	case formatMassdns:
+		err = inv.importMassdns(content)
+	default:
+		err = inv.importList(content)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("Imported %s as %s output, %d new hosts, %d invalid host names", file, format,
+		len(inv.hosts)-before, inv.rejected-rejected)
+	return nil
+}
+
+// detectFormat detects the format of an enumerator or resolver output. A JSON
+// array is a certificate transparency dump. JSON lines with a resolver or a
+// status code are from dnsx, with a name and answer data from massdns, with
+// only a name from amass and with a host from subfinder. Lines with a name, a
+// record type and data are from massdns as well. Everything else is a list of
+// hosts.
+func detectFormat(content []byte) string {
+	content = bytes.TrimSpace(content)
+	if bytes.HasPrefix(content, []byte("[")) {
+		return formatCrtsh
+	}
+	line := content
+	if newline := bytes.IndexByte(line, '\n'); newline >= 0 {
+		line = line[:newline]
+	}
+	if bytes.HasPrefix(content, []byte("{")) {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(line, &fields) == nil {
+			_, name := fields["name"]
+			_, host := fields["host"]
+			_, resolver := fields["resolver"]
+			_, status := fields["status_code"]
+			_, data := fields["data"]
+			switch {
+			case host && (resolver || status):
+				return formatDnsx
+			case name && data:
+				return formatMassdns
+			case name:
+				return formatAmass
+			case host:
+				return formatSubfinder
+			}
+		}
+	}
+	if _, _, ok := parseMassdnsLine(string(line)); ok {
+		return formatMassdns
+	}
+	return formatList
+}
+
+// importSubfinder imports the JSON lines of subfinder (-oJ).
+func (inv *Inventory) importSubfinder(content []byte) error {
+	return eachJSONLine(content, func(line []byte) error {
+		var entry struct {
+			Host string `json:"host"`
+			IP   string `json:"ip"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		if entry.IP != "" {
+			inv.add(entry.Host, formatSubfinder, entry.IP)
+		} else {
+			inv.add(entry.Host, formatSubfinder)
+		}
+		return nil
+	})
+}
+
+// importAmass imports the JSON lines of amass (-json).
+func (inv *Inventory) importAmass(content []byte) error {
+	return eachJSONLine(content, func(line []byte) error {
+		var entry struct {
+			Name      string `json:"name"`
+			Addresses []struct {
+				IP string `json:"ip"`
+			} `json:"addresses"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		var addresses []string
+		for _, address := range entry.Addresses {
+			addresses = append(addresses, address.IP)
+		}
+		inv.add(entry.Name, formatAmass, addresses...)
+		return nil
+	})
+}
+
+// importCrtsh imports the JSON output of crt.sh (?output=json). Every entry
+// contains the common name and the names of the certificate separated by new
+// lines.
+func (inv *Inventory) importCrtsh(content []byte) error {
+	var entries []struct {
+		CommonName string `json:"common_name"`
+		NameValue  string `json:"name_value"`
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		names := append([]string{entry.CommonName}, strings.Split(entry.NameValue, "\n")...)
+		for _, name := range names {
+			if strings.TrimSpace(name) != "" {
+				inv.add(name, formatCrtsh)
+			}
+		}
+	}
+	return nil
+}
+
+// importList imports a list of host names or URLs, one per line, as written
+// by assetfinder, findomain and most other tools.
+func (inv *Inventory) importList(content []byte) error {
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inv.add(line, formatList)
+	}
+	return sc.Err()
+}
+
+func eachJSONLine(content []byte, fn func(line []byte) error) error {
+	br := bufio.NewReader(bytes.NewReader(content))
+	for number := 1; ; number++ {
+		line, err := br.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if decodeErr := fn(trimmed); decodeErr != nil {
+				log.Warnf("Skipping malformed line %d: %v", number, decodeErr)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// Hosts returns all hosts with their sources and httpx results.
+func (inv *Inventory) Hosts() []*HostEntry {
+	for _, entry := range inv.hosts {
+		sort.Strings(entry.Sources)
+	}
+	if inv.hosts == nil {
+		return []*HostEntry{}
+	}
+	return inv.hosts
+}
+
+// CNAMEs returns the imported CNAMEs of the host.
+func (inv *Inventory) CNAMEs(host string) []string {
+	if entry, ok := inv.byHost[host]; ok {
+		return entry.CNAMEs
+	}
+	return nil
+}
+
+// Contains checks if the host has been found.
+func (inv *Inventory) Contains(host string) bool {
+	_, ok := inv.byHost[host]
+	return ok
+}
@@ -0,0 +1,129 @@
+package purify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	subfinderOutput = `{"host":"www.example.com","input":"example.com","source":"crtsh"}
+{"host":"API.example.com","input":"example.com","source":"dnsdumpster","ip":"192.0.2.10"}
+not json
+{"host":"www.example.com","input":"example.com","source":"alienvault"}`
+
+	amassOutput = `{"name":"vpn.example.com","domain":"example.com","addresses":[{"ip":"192.0.2.20","cidr":"192.0.2.0/24"},{"ip":"2001:db8::20","cidr":"2001:db8::/32"}]}
+{"name":"mail.example.com","domain":"example.com","addresses":[]}`
+
+	crtshOutput = `[
+  {"issuer_name":"C=US, O=Let's Encrypt, CN=R3","common_name":"example.com","name_value":"example.com\nwww.example.com"},
+  {"issuer_name":"C=US, O=Let's Encrypt, CN=R3","common_name":"*.dev.example.com","name_value":"*.dev.example.com\n*.*.dev.example.com\nci.dev.example.com"},
+  {"issuer_name":"C=US, O=Let's Encrypt, CN=R3","common_name":"shop.example.com","name_value":""}
+]`
+
+	listOutput = `# assetfinder
+www.example.com
+https://portal.example.com:8443/login
+
+under_score.example.com
+`
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{subfinderOutput, formatSubfinder},
+		{amassOutput, formatAmass},
+		{crtshOutput, formatCrtsh},
+		{"\n  " + crtshOutput, formatCrtsh},
+		{listOutput, formatList},
+		{"{not json\n", formatList},
+		{"", formatList},
+	}
+	for _, tt := range tests {
+		if got := detectFormat([]byte(tt.content)); got != tt.want {
+			t.Errorf("detectFormat(%.40q) = %s, want %s", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestImportFormats(t *testing.T) {
+	tests := []struct {
+		name      string
+		importer  func(inv *Inventory, content []byte) error
+		content   string
+		hosts     []string
+		addresses map[string][]string
+		rejected  int
+	}{
+		{"subfinder", (*Inventory).importSubfinder, subfinderOutput,
+			[]string{"www.example.com", "api.example.com"},
+			map[string][]string{"api.example.com": {"192.0.2.10"}}, 0},
+		{"amass", (*Inventory).importAmass, amassOutput,
+			[]string{"vpn.example.com", "mail.example.com"},
+			map[string][]string{"vpn.example.com": {"192.0.2.20", "2001:db8::20"}}, 0},
+		{"crtsh", (*Inventory).importCrtsh, crtshOutput,
+			[]string{"example.com", "www.example.com", "dev.example.com", "ci.dev.example.com", "shop.example.com"},
+			nil, 0},
+		{"list", (*Inventory).importList, listOutput,
+			[]string{"www.example.com", "portal.example.com"}, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := NewInventory()
+			if err := tt.importer(inv, []byte(tt.content)); err != nil {
+				t.Fatal(err)
+			}
+			var hosts []string
+			for _, entry := range inv.Hosts() {
+				hosts = append(hosts, entry.Host)
+				if !reflect.DeepEqual(entry.Sources, []string{tt.name}) {
+					t.Errorf("sources of %s = %q, want %s", entry.Host, entry.Sources, tt.name)
+				}
+				if want := tt.addresses[entry.Host]; !reflect.DeepEqual(entry.Addresses, want) {
+					t.Errorf("addresses of %s = %q, want %q", entry.Host, entry.Addresses, want)
+				}
+			}
+			if !reflect.DeepEqual(hosts, tt.hosts) {
+				t.Errorf("hosts = %q, want %q", hosts, tt.hosts)
+			}
+			if inv.rejected != tt.rejected {
+				t.Errorf("rejected %d host names, want %d", inv.rejected, tt.rejected)
+			}
+		})
+	}
+
+	if err := NewInventory().importCrtsh([]byte(`{"common_name":"example.com"}`)); err == nil {
+		t.Error("crt.sh output without array returned no error")
+	}
+}
+
+func TestImportFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"subfinder.json": subfinderOutput, "hosts.txt": listOutput, "amass.txt": amassOutput}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inv := NewInventory()
+	err := inv.ImportFiles(filepath.Join(dir, "subfinder.json") + ", " + filepath.Join(dir, "hosts.txt") +
+		",amass:" + filepath.Join(dir, "amass.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := inv.byHost["www.example.com"]; entry == nil || !reflect.DeepEqual(entry.Sources, []string{"subfinder", "list"}) {
+		t.Errorf("www.example.com = %+v, want sources subfinder and list", entry)
+	}
+	if entry := inv.byHost["vpn.example.com"]; entry == nil || entry.Sources[0] != "amass" {
+		t.Errorf("vpn.example.com = %+v, want source amass", entry)
+	}
+
+	if err := NewInventory().ImportFiles(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("missing file returned no error")
+	}
+}
@@ -0,0 +1,218 @@
+package purify
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keyPresets are the predefined duplicate key definitions.
+var keyPresets = map[string]string{
+	"default": "status_code,content_length,title",
+	"length":  "status_code,content_length",
+	"loose":   "status_code,content_length~50,title:lower:nohost",
+	"strict":  "status_code,content_length,title,webserver,body_sha256",
+	"body":    "status_code,body_sha256",
+	"favicon": "status_code,favicon",
+	"cert":    "status_code,cert_sha256,title",
+	"product": "status_code,product",
+}
+
+// numericFields are the fields which support a tolerance (~N).
+var numericFields = map[string]func(r *Record) int{
+	"status_code":    func(r *Record) int { return r.StatusCode },
+	"content_length": func(r *Record) int { return r.ContentLength },
+	"words":          func(r *Record) int { return r.Words },
+	"lines":          func(r *Record) int { return r.Lines },
+}
+
+var spaceRegex = regexp.MustCompile(`\s+`)
+
+// keyField is a single field of the duplicate key together with its
+// normalisation.
+type keyField struct {
+	name      string
+	value     func(r *Record) string
+	number    func(r *Record) int
+	tolerance int
+	lower     bool
+	noHost    bool
+	trim      bool
+}
+
+// GroupKey defines which records are duplicates of each other. Records are
+// duplicates if all normalised fields are equal and all numeric fields with a
+// tolerance differ by at most the tolerance from the first record of the
+// group.
+type GroupKey struct {
+	definition string
+	fields     []*keyField
+}
+
+// ParseGroupKey parses a preset name or a comma separated list of fields. Each
+// field can be followed by ~N to allow a difference of N for numeric fields
+// and by the modifiers :lower (case-insensitive), :nohost (remove the host
+// name and its first label) and :trim (collapse white space), for example
+// "status_code,content_length~20,title:lower:nohost".
+func ParseGroupKey(definition string) (*GroupKey, error) {
+	if preset, ok := keyPresets[definition]; ok {
+		definition = preset
+	}
+	key := &GroupKey{definition: definition}
+
+	for _, item := range strings.Split(definition, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		field := &keyField{name: parts[0]}
+		if tilde := strings.Index(field.name, "~"); tilde >= 0 {
+			tolerance, err := strconv.Atoi(field.name[tilde+1:])
+			if err != nil || tolerance < 0 {
+				return nil, fmt.Errorf("invalid tolerance in duplicate key field %q", item)
+			}
+			field.name = field.name[:tilde]
+			field.tolerance = tolerance
+		}
+
+		value, ok := recordFields[field.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown duplicate key field %q, known fields are %s", field.name,
+				strings.Join(fieldNames(), ","))
+		}
+		field.value = value
+		if field.tolerance > 0 {
+			if field.number, ok = numericFields[field.name]; !ok {
+				return nil, fmt.Errorf("tolerance is only supported for numeric fields, not %q", field.name)
+			}
+		}
+
+		for _, modifier := range parts[1:] {
+			switch modifier {
+			case "lower":
+				field.lower = true
+			case "nohost":
+				field.noHost = true
+			case "trim":
+				field.trim = true
+			default:
+				return nil, fmt.Errorf("unknown modifier %q of duplicate key field %q", modifier, field.name)
+			}
+		}
+		key.fields = append(key.fields, field)
+	}
+
+	if len(key.fields) == 0 {
+		return nil, fmt.Errorf("duplicate key %q contains no fields", definition)
+	}
+	return key, nil
+}
+
+// PresetNames returns the sorted names of the duplicate key presets.
+func PresetNames() []string {
+	var names []string
+	for name := range keyPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exact returns the normalised values of all fields without tolerance.
+func (k *GroupKey) exact(r *Record) string {
+	var values []string
+	for _, field := range k.fields {
+		if field.tolerance > 0 {
+			continue
+		}
+		values = append(values, field.normalise(r))
+	}
+	return strings.Join(values, "|")
+}
+
+// describe returns the key of the group represented by the record. Fields with
+// tolerance are written as value~tolerance.
+func (k *GroupKey) describe(r *Record) string {
+	var values []string
+	for _, field := range k.fields {
+		if field.tolerance > 0 {
+			values = append(values, fmt.Sprintf("%d~%d", field.number(r), field.tolerance))
+		} else {
+			values = append(values, field.normalise(r))
+		}
+	}
+	return strings.Join(values, "|")
+}
+
+// within checks if all fields with tolerance of the record are close enough
+// to the ones of the representative.
+func (k *GroupKey) within(representative *Record, r *Record) bool {
+	for _, field := range k.fields {
+		if field.tolerance == 0 {
+			continue
+		}
+		diff := field.number(representative) - field.number(r)
+		if diff < -field.tolerance || diff > field.tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func (field *keyField) normalise(r *Record) string {
+	value := field.value(r)
+	if field.noHost {
+		value = withoutHost(value, HostName(r))
+	}
+	if field.lower {
+		value = strings.ToLower(value)
+	}
+	if field.trim {
+		value = strings.TrimSpace(spaceRegex.ReplaceAllString(value, " "))
+	}
+	return value
+}
+
+// withoutHost removes the host name and its first label from the value and
+// collapses the remaining white space. The label is only removed as a whole
+// word, so a short label like "a" doesn't remove letters of other words.
+func withoutHost(value string, host string) string {
+	value = replaceFold(value, host, false)
+	if dot := strings.Index(host, "."); dot > 0 {
+		value = replaceFold(value, host[:dot], true)
+	}
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(value, " "))
+}
+
+// replaceFold removes all case-insensitive occurrences of old from s, with
+// wholeWord only if they aren't part of a longer word. It is called for every
+// record, so it compares the bytes instead of compiling a regular expression.
+func replaceFold(s string, old string, wholeWord bool) string {
+	if old == "" {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		end := i + len(old)
+		if end <= len(s) && strings.EqualFold(s[i:end], old) &&
+			(!wholeWord || !isWordByte(s, i-1) && !isWordByte(s, end)) {
+			i = end
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// isWordByte reports whether the byte at i is a word character (\w).
+func isWordByte(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,75 @@
+package purify
+
+import "testing"
+
+func TestParseGroupKey(t *testing.T) {
+	tests := []struct {
+		definition string
+		fields     int
+		wantErr    bool
+	}{
+		{"default", 3, false},
+		{"loose", 3, false},
+		{"status_code, title:lower:trim", 2, false},
+		{"content_length~20", 1, false},
+		{"", 0, true},
+		{"nope", 0, true},
+		{"title~5", 0, true},
+		{"content_length~x", 0, true},
+		{"title:upper", 0, true},
+	}
+	for _, tt := range tests {
+		key, err := ParseGroupKey(tt.definition)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGroupKey(%q) error = %v, want error %v", tt.definition, err, tt.wantErr)
+			continue
+		}
+		if err == nil && len(key.fields) != tt.fields {
+			t.Errorf("ParseGroupKey(%q) has %d fields, want %d", tt.definition, len(key.fields), tt.fields)
+		}
+	}
+}
+
+func TestGroupKeyGroup(t *testing.T) {
+	records := []*Record{
+		{URL: "https://a.example.com", StatusCode: 200, ContentLength: 1000, Title: "Welcome to a.example.com"},
+		{URL: "https://bb.example.com", StatusCode: 200, ContentLength: 1030, Title: "WELCOME TO  bb.example.com"},
+		{URL: "https://c.example.com", StatusCode: 200, ContentLength: 1100, Title: "Welcome to c.example.com"},
+		{URL: "https://d.example.com", StatusCode: 404, ContentLength: 1000, Title: "Welcome to d.example.com"},
+	}
+	key, err := ParseGroupKey("loose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := key.Group(records)
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(groups))
+	}
+	if len(groups[0].Records) != 2 || groups[0].Records[1] != records[1] {
+		t.Errorf("first group = %v, want a and bb", groups[0].Records)
+	}
+	if groups[0].Key != "200|1000~50|welcome to" {
+		t.Errorf("key = %q, want 200|1000~50|welcome to", groups[0].Key)
+	}
+}
+
+func TestWithoutHost(t *testing.T) {
+	tests := []struct {
+		value string
+		host  string
+		want  string
+	}{
+		{"Welcome to WWW.Example.com", "www.example.com", "Welcome to"},
+		{"www - Login", "www.example.com", "- Login"},
+		{"Default page", "a.example.com", "Default page"},
+		{"Shop a", "a.example.com", "Shop"},
+		{"shop_a and a-b", "a.example.com", "shop_a and -b"},
+		{"Grüße von b.example.com", "b.example.com", "Grüße von"},
+		{"Title", "", "Title"},
+	}
+	for _, tt := range tests {
+		if got := withoutHost(tt.value, tt.host); got != tt.want {
+			t.Errorf("withoutHost(%q, %q) = %q, want %q", tt.value, tt.host, got, tt.want)
+		}
+	}
+}
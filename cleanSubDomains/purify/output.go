@@ -0,0 +1,183 @@
+package purify
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultColumns are the columns of the CSV output if none are chosen.
+const DefaultColumns = "url,host,status_code,content_length,title,webserver,tech"
+
+// Report is the JSON report of a purification run.
+type Report struct {
+	Summary   ReportSummary      `json:"summary"`
+	Groups    []GroupReport      `json:"groups"`
+	Wildcards []*WildcardZone    `json:"wildcards,omitempty"`
+	Scope     []*ScopeRuleReport `json:"scope,omitempty"`
+}
+
+// ReportSummary counts the records of a purification run.
+type ReportSummary struct {
+	Records           int `json:"records"`
+	Kept              int `json:"kept"`
+	DroppedDuplicates int `json:"dropped_duplicates"`
+	DroppedWildcards  int `json:"dropped_wildcards"`
+	DroppedScope      int `json:"dropped_scope"`
+	Groups            int `json:"groups"`
+}
+
+// GroupReport lists the kept and the dropped records of a duplicate group.
+type GroupReport struct {
+	Key     string          `json:"key"`
+	Size    int             `json:"size"`
+	Kept    []KeptRecord    `json:"kept"`
+	Dropped []DroppedRecord `json:"dropped,omitempty"`
+}
+
+// KeptRecord is a record kept as representative of a group together with its
+// score and the explanation why.
+type KeptRecord struct {
+	URL    string  `json:"url"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// KeptRecords returns the kept records of all groups in input order.
+func KeptRecords(records []*Record, groups []*Group) []*Record {
+	keep := make(map[*Record]bool)
+	for _, g := range groups {
+		for _, r := range g.Kept {
+			keep[r] = true
+		}
+	}
+	var kept []*Record
+	for _, r := range records {
+		if keep[r] {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// HostWriter writes the unique host names of the records, one per line.
+type HostWriter struct{}
+
+func (HostWriter) Write(w io.Writer, records []*Record) (int, error) {
+	return writeList(w, records, HostName)
+}
+
+// URLWriter writes the unique URLs of the records, one per line.
+type URLWriter struct{}
+
+func (URLWriter) Write(w io.Writer, records []*Record) (int, error) {
+	return writeList(w, records, func(r *Record) string { return r.URL })
+}
+
+func writeList(w io.Writer, records []*Record, value func(r *Record) string) (int, error) {
+	written := make(map[string]bool)
+	bw := bufio.NewWriter(w)
+	for _, r := range records {
+		entry := value(r)
+		if entry == "" || written[entry] {
+			continue
+		}
+		written[entry] = true
+		fmt.Fprintln(bw, entry)
+	}
+	return len(written), bw.Flush()
+}
+
+// JSONLinesWriter writes the original httpx JSON lines of the records, so
+// that the output can be used by tools consuming httpx output.
+type JSONLinesWriter struct{}
+
+func (JSONLinesWriter) Write(w io.Writer, records []*Record) (int, error) {
+	bw := bufio.NewWriter(w)
+	for _, r := range records {
+		line := r.raw
+		if line == nil {
+			line, _ = json.Marshal(r)
+		}
+		bw.Write(line)
+		bw.WriteByte('\n')
+	}
+	return len(records), bw.Flush()
+}
+
+// ValidateColumns checks that all comma separated columns can be written.
+func ValidateColumns(columns string) ([]string, error) {
+	var result []string
+	for _, column := range strings.Split(columns, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		if _, ok := recordFields[column]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q, known columns are %s", column, strings.Join(fieldNames(), ","))
+		}
+		result = append(result, column)
+	}
+	return result, nil
+}
+
+// CSVWriter writes the columns of the records as CSV. The columns must have
+// been checked with ValidateColumns.
+type CSVWriter struct {
+	Columns []string
+}
+
+func (c CSVWriter) Write(w io.Writer, records []*Record) (int, error) {
+	cw := csv.NewWriter(w)
+	cw.Write(c.Columns)
+	for _, r := range records {
+		row := make([]string, len(c.Columns))
+		for i, column := range c.Columns {
+			row[i] = recordFields[column](r)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return len(records), cw.Error()
+}
+
+// CreateReport creates the report of all groups, including the ones of unique
+// hosts, and all records dropped because of wildcards or the scope.
+func CreateReport(records int, kept []*Record, groups []*Group, zones []*WildcardZone,
+	scopeReports []*ScopeRuleReport) *Report {
+	rep := &Report{
+		Summary:   ReportSummary{Records: records, Kept: len(kept)},
+		Groups:    []GroupReport{},
+		Wildcards: zones,
+		Scope:     scopeReports,
+	}
+
+	for _, g := range groups {
+		rep.Summary.DroppedDuplicates += len(g.Dropped)
+		gr := GroupReport{Key: g.Key, Size: len(g.Records)}
+		for _, r := range g.Kept {
+			s := g.selections[r]
+			gr.Kept = append(gr.Kept, KeptRecord{URL: r.URL, Score: s.Score, Reason: s.Reason})
+		}
+		reason := fmt.Sprintf("duplicate (%s)", g.Key)
+		if len(g.Kept) > 0 {
+			reason = fmt.Sprintf("duplicate of %s (%s)", g.Kept[0].URL, g.Key)
+		}
+		for _, r := range g.Dropped {
+			gr.Dropped = append(gr.Dropped, DroppedRecord{URL: r.URL, Host: HostName(r), Reason: reason})
+		}
+		rep.Groups = append(rep.Groups, gr)
+	}
+	rep.Summary.Groups = len(rep.Groups)
+
+	for _, z := range zones {
+		rep.Summary.DroppedWildcards += len(z.Dropped)
+	}
+	for _, sr := range scopeReports {
+		rep.Summary.DroppedScope += len(sr.Removed)
+	}
+	return rep
+}
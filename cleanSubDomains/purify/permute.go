@@ -0,0 +1,238 @@
+package purify
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	numberRegex = regexp.MustCompile(`[0-9]+`)
+	labelRegex  = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+)
+
+// Vocabulary contains the words mined from the labels of host names together
+// with how often they have been seen.
+type Vocabulary struct {
+	counts map[string]int
+}
+
+// MineVocabulary collects the labels of the subdomains and their parts. A
+// label like api-staging2 adds api-staging2, api and staging.
+func MineVocabulary(hosts []string) *Vocabulary {
+	v := &Vocabulary{counts: make(map[string]int)}
+	for _, host := range hosts {
+		parts := SplitHost(host)
+		if parts.Subdomain == "" {
+			continue
+		}
+		for _, label := range strings.Split(parts.Subdomain, ".") {
+			words := map[string]bool{label: true}
+			for _, token := range strings.Split(label, "-") {
+				token = strings.Trim(numberRegex.ReplaceAllString(token, ""), "-")
+				if len(token) > 1 {
+					words[token] = true
+				}
+			}
+			for word := range words {
+				v.counts[word]++
+			}
+		}
+	}
+	return v
+}
+
+// Words returns at most limit words ordered by how often they have been seen.
+func (v *Vocabulary) Words(limit int) []string {
+	var words []string
+	for word := range v.counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if v.counts[words[i]] != v.counts[words[j]] {
+			return v.counts[words[i]] > v.counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if limit > 0 && len(words) > limit {
+		words = words[:limit]
+	}
+	return words
+}
+
+// permutator generates new host names from known ones, similar to altdns and
+// gotator.
+type permutator struct {
+	known      map[string]bool
+	seen       map[string]bool
+	candidates []string
+	limit      int
+}
+
+// Permute generates at most limit candidates from the hosts and words. The
+// strategies are applied in the order of their success rate, each one to all
+// hosts, before the next one is used.
+//
+//  1. Numbers are incremented and decremented (vpn2 -> vpn3, vpn1, vpn4, vpn0)
+//  2. Parts of labels are replaced by words (api-staging -> api-dev)
+//  3. The first label is joined with words (api -> api-dev, dev-api)
+//  4. Words are inserted as new labels (api.example.com -> dev.api.example.com)
+func Permute(hosts []string, words []string, limit int) []string {
+	p := &permutator{known: make(map[string]bool), seen: make(map[string]bool), limit: limit}
+	type split struct {
+		labels []string
+		domain string
+	}
+	var splits []split
+	for _, host := range hosts {
+		p.known[host] = true
+		parts := SplitHost(host)
+		if parts.Domain == "" {
+			continue
+		}
+		var labels []string
+		if parts.Subdomain != "" {
+			labels = strings.Split(parts.Subdomain, ".")
+		}
+		splits = append(splits, split{labels: labels, domain: parts.Domain})
+	}
+
+	for _, s := range splits {
+		for i, label := range s.labels {
+			for _, alternative := range alterNumbers(label) {
+				p.add(replaceLabel(s.labels, i, alternative), s.domain)
+			}
+		}
+	}
+	for _, s := range splits {
+		for i, label := range s.labels {
+			tokens := strings.Split(label, "-")
+			for t := range tokens {
+				for _, word := range words {
+					if containsWord(tokens, word) || len(tokens) > 1 && strings.Contains(word, "-") {
+						continue
+					}
+					replaced := append([]string(nil), tokens...)
+					replaced[t] = word
+					p.add(replaceLabel(s.labels, i, strings.Join(replaced, "-")), s.domain)
+				}
+			}
+		}
+	}
+	for _, s := range splits {
+		if len(s.labels) == 0 {
+			continue
+		}
+		tokens := strings.Split(s.labels[0], "-")
+		for _, word := range words {
+			if containsWord(tokens, word) || strings.Contains(word, s.labels[0]) {
+				continue
+			}
+			p.add(replaceLabel(s.labels, 0, s.labels[0]+"-"+word), s.domain)
+			p.add(replaceLabel(s.labels, 0, word+"-"+s.labels[0]), s.domain)
+		}
+	}
+	for _, s := range splits {
+		for _, word := range words {
+			if len(s.labels) > 0 && word == s.labels[0] {
+				continue
+			}
+			p.add(append([]string{word}, s.labels...), s.domain)
+		}
+	}
+
+	if p.full() {
+		log.Warnf("Stopped generating permutations after %d candidates", p.limit)
+	}
+	return p.candidates
+}
+
+func (p *permutator) full() bool {
+	return p.limit > 0 && len(p.candidates) >= p.limit
+}
+
+// add adds the host name built from the labels and the domain, if it is valid,
+// not known yet and doesn't repeat a label.
+func (p *permutator) add(labels []string, domain string) {
+	if p.full() {
+		return
+	}
+	host := strings.Join(append(append([]string(nil), labels...), domain), ".")
+	if p.known[host] || p.seen[host] {
+		return
+	}
+	p.seen[host] = true
+	for i, label := range labels {
+		if !labelRegex.MatchString(label) || i > 0 && labels[i-1] == label {
+			return
+		}
+	}
+	if validateHost(host) != nil {
+		return
+	}
+	p.candidates = append(p.candidates, host)
+}
+
+// alterNumbers returns the label with every number incremented and
+// decremented by up to two, keeping leading zeros.
+func alterNumbers(label string) []string {
+	var alternatives []string
+	for _, loc := range numberRegex.FindAllStringIndex(label, -1) {
+		digits := label[loc[0]:loc[1]]
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			continue
+		}
+		for _, delta := range []int{1, -1, 2, -2} {
+			if n+delta < 0 {
+				continue
+			}
+			number := fmt.Sprintf("%0*d", len(digits), n+delta)
+			alternatives = append(alternatives, label[:loc[0]]+number+label[loc[1]:])
+		}
+	}
+	return alternatives
+}
+
+// containsWord checks if the word is one of the tokens of a label, or contains
+// all of them.
+func containsWord(tokens []string, word string) bool {
+	for _, token := range tokens {
+		if token == word {
+			return true
+		}
+	}
+	return word == strings.Join(tokens, "-")
+}
+
+func replaceLabel(labels []string, i int, label string) []string {
+	replaced := append([]string(nil), labels...)
+	replaced[i] = label
+	return replaced
+}
+
+// ReadWords reads the additional words, one per line. Words which can't be
+// used as label are skipped.
+func ReadWords(wordFile string) ([]string, error) {
+	file, err := os.Open(wordFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		word := strings.ToLower(strings.TrimSpace(sc.Text()))
+		if labelRegex.MatchString(word) {
+			words = append(words, word)
+		}
+	}
+	return words, sc.Err()
+}
@@ -0,0 +1,70 @@
+package purify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAlterNumbers(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"vpn2", []string{"vpn3", "vpn1", "vpn4", "vpn0"}},
+		{"app01", []string{"app02", "app00", "app03"}},
+		{"a1b9", []string{"a2b9", "a0b9", "a3b9", "a1b10", "a1b8", "a1b11", "a1b7"}},
+		{"www", nil},
+	}
+	for _, tt := range tests {
+		if got := alterNumbers(tt.label); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("alterNumbers(%s) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestMineVocabulary(t *testing.T) {
+	v := MineVocabulary([]string{"api-staging2.example.com", "api.example.com", "dev.api.example.com",
+		"www.example.com", "example.com"})
+	if got, want := v.Words(0), []string{"api", "api-staging2", "dev", "staging", "www"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("words = %q, want %q", got, want)
+	}
+	if got, want := v.Words(2), []string{"api", "api-staging2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("limited words = %q, want %q", got, want)
+	}
+}
+
+func TestPermute(t *testing.T) {
+	hosts := []string{"vpn1.example.com", "vpn2.example.com", "api-staging.example.com", "dev.example.com"}
+	words := []string{"dev"}
+
+	candidates := Permute(hosts, words, 0)
+	seen := make(map[string]bool)
+	for _, host := range candidates {
+		if seen[host] {
+			t.Errorf("%s generated twice", host)
+		}
+		seen[host] = true
+	}
+	for _, host := range hosts {
+		if seen[host] {
+			t.Errorf("known host %s generated", host)
+		}
+	}
+	for _, host := range []string{"vpn0.example.com", "vpn3.example.com", "vpn4.example.com", "api-dev.example.com",
+		"dev-staging.example.com", "vpn1-dev.example.com", "dev-vpn1.example.com", "dev.vpn1.example.com"} {
+		if !seen[host] {
+			t.Errorf("%s not generated", host)
+		}
+	}
+	for _, host := range []string{"dev.dev.example.com", "dev-dev.example.com", "api-staging-dev-dev.example.com"} {
+		if seen[host] {
+			t.Errorf("%s generated", host)
+		}
+	}
+
+	// The limit stops after the first strategy here
+	limited := Permute(hosts, words, 3)
+	if want := []string{"vpn0.example.com", "vpn3.example.com", "vpn4.example.com"}; !reflect.DeepEqual(limited, want) {
+		t.Errorf("limited candidates = %q, want %q", limited, want)
+	}
+}
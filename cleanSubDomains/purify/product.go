@@ -0,0 +1,174 @@
+package purify
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed favicons.txt
+var builtinFavicons string
+
+// FaviconTable maps favicon hashes to product names.
+type FaviconTable map[string]string
+
+// LoadFile adds the entries of the table file.
+func (t FaviconTable) LoadFile(tableFile string) error {
+	file, err := os.Open(tableFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return t.Load(file, tableFile)
+}
+
+// LoadBuiltin adds the entries of the built-in table.
+func (t FaviconTable) LoadBuiltin() error {
+	return t.Load(strings.NewReader(builtinFavicons), "favicons.txt")
+}
+
+// Load adds the entries of a table. Every line contains the favicon hash
+// followed by the product name. Empty lines and lines starting with # are
+// ignored. Hashes which are already known are not overwritten.
+func (t FaviconTable) Load(reader io.Reader, source string) error {
+	sc := bufio.NewScanner(reader)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return fmt.Errorf("%s:%d: expected favicon hash and product name", source, line)
+		}
+		if _, ok := t[fields[0]]; !ok {
+			t[fields[0]] = strings.Join(fields[1:], " ")
+		}
+	}
+	return sc.Err()
+}
+
+// techNames returns the technologies of the record without versions, e.g.
+// Nginx for Nginx:1.19.
+func techNames(r *Record) []string {
+	var names []string
+	for _, tech := range r.Tech {
+		if colon := strings.Index(tech, ":"); colon >= 0 {
+			tech = tech[:colon]
+		}
+		if tech = strings.TrimSpace(tech); tech != "" {
+			names = appendUnique(names, tech)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TagProducts tags every record with the products identified by its favicon
+// hash and its technologies.
+func TagProducts(records []*Record, favicons FaviconTable) {
+	for _, r := range records {
+		r.products = nil
+		if product, ok := favicons[r.Favicon]; ok && r.Favicon != "" {
+			r.products = append(r.products, product)
+		}
+		for _, name := range techNames(r) {
+			r.products = appendUnique(r.products, name)
+		}
+	}
+}
+
+// ProductFilter keeps records by product names, technologies or favicon
+// hashes.
+type ProductFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewProductFilter returns a filter of the comma separated included and
+// excluded products.
+func NewProductFilter(include string, exclude string) *ProductFilter {
+	return &ProductFilter{include: splitProducts(include), exclude: splitProducts(exclude)}
+}
+
+func splitProducts(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Empty checks if the filter has no products.
+func (f *ProductFilter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// matchesProduct checks if one of the values is the favicon hash or a product
+// of the record, compared case-insensitive.
+func matchesProduct(r *Record, values []string) bool {
+	for _, value := range values {
+		if value == r.Favicon {
+			return true
+		}
+		for _, product := range r.products {
+			if strings.ToLower(product) == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter removes all records which don't match an included product or which
+// match an excluded one.
+func (f *ProductFilter) Filter(records []*Record) []*Record {
+	var remaining []*Record
+	for _, r := range records {
+		if len(f.include) > 0 && !matchesProduct(r, f.include) {
+			continue
+		}
+		if matchesProduct(r, f.exclude) {
+			continue
+		}
+		remaining = append(remaining, r)
+	}
+	log.Infof("Product filter removed %d records", len(records)-len(remaining))
+	return remaining
+}
+
+// ProductHosts lists the hosts a product has been identified on.
+type ProductHosts struct {
+	Product string   `json:"product"`
+	Hosts   []string `json:"hosts"`
+}
+
+// GroupProducts lists the hosts per product, products found on most hosts
+// first.
+func GroupProducts(records []*Record) []ProductHosts {
+	var groups []ProductHosts
+	byProduct := make(map[string]int)
+	for _, r := range records {
+		for _, product := range r.products {
+			i, ok := byProduct[product]
+			if !ok {
+				i = len(groups)
+				byProduct[product] = i
+				groups = append(groups, ProductHosts{Product: product})
+			}
+			groups[i].Hosts = appendUnique(groups[i].Hosts, HostName(r))
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Hosts) > len(groups[j].Hosts)
+	})
+	return groups
+}
@@ -0,0 +1,108 @@
+package purify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFaviconTable(t *testing.T) {
+	table := FaviconTable{}
+	custom := "# own products\n\n81586312 Build Server\n-1000 Internal   Portal\n"
+	if err := table.Load(strings.NewReader(custom), "custom.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.LoadBuiltin(); err != nil {
+		t.Fatal(err)
+	}
+	// Entries loaded first take precedence over the built-in table
+	for hash, want := range map[string]string{"81586312": "Build Server", "-1000": "Internal Portal",
+		"1278323681": "GitLab"} {
+		if got := table[hash]; got != want {
+			t.Errorf("table[%s] = %q, want %q", hash, got, want)
+		}
+	}
+
+	err := FaviconTable{}.Load(strings.NewReader("# header\n81586312\n"), "broken.txt")
+	if err == nil || !strings.Contains(err.Error(), "broken.txt:2") {
+		t.Errorf("error = %v, want an error of broken.txt:2", err)
+	}
+}
+
+func TestTagProducts(t *testing.T) {
+	table := FaviconTable{"81586312": "Jenkins"}
+	records := []*Record{
+		{URL: "https://ci.example.com", Favicon: "81586312", Tech: []string{"Jetty:9.4", "Java", "Jenkins:2.3"}},
+		{URL: "https://www.example.com", Favicon: "42", Tech: []string{"Nginx:1.19", " ", "Nginx:1.21"}},
+		{URL: "https://api.example.com"},
+	}
+	TagProducts(records, table)
+	want := [][]string{{"Jenkins", "Java", "Jetty"}, {"Nginx"}, nil}
+	for i, r := range records {
+		if !reflect.DeepEqual(r.products, want[i]) {
+			t.Errorf("products of %s = %q, want %q", r.URL, r.products, want[i])
+		}
+	}
+
+	// Tagging again replaces the previous products
+	TagProducts(records, FaviconTable{})
+	if want := []string{"Java", "Jenkins", "Jetty"}; !reflect.DeepEqual(records[0].products, want) {
+		t.Errorf("products after retagging = %q, want %q", records[0].products, want)
+	}
+}
+
+func TestProductFilter(t *testing.T) {
+	records := []*Record{
+		{URL: "https://ci.example.com", Favicon: "81586312", Tech: []string{"Jetty"}},
+		{URL: "https://www.example.com", Favicon: "42", Tech: []string{"Nginx:1.19"}},
+		{URL: "https://api.example.com", Tech: []string{"Nginx", "Express"}},
+		{URL: "https://mail.example.com"},
+	}
+	TagProducts(records, FaviconTable{"81586312": "Jenkins"})
+
+	tests := []struct {
+		include string
+		exclude string
+		want    []string
+	}{
+		{"", "", []string{"ci", "www", "api", "mail"}},
+		{"jenkins", "", []string{"ci"}},
+		{" NGINX , 42", "", []string{"www", "api"}},
+		{"", "nginx", []string{"ci", "mail"}},
+		{"nginx", "express", []string{"www"}},
+		{"", "42,jetty", []string{"api", "mail"}},
+	}
+	for _, tt := range tests {
+		f := NewProductFilter(tt.include, tt.exclude)
+		if empty := tt.include == "" && tt.exclude == ""; f.Empty() != empty {
+			t.Errorf("NewProductFilter(%q, %q).Empty() = %v, want %v", tt.include, tt.exclude, f.Empty(), empty)
+		}
+		var got []string
+		for _, r := range f.Filter(records) {
+			got = append(got, strings.TrimSuffix(HostName(r), ".example.com"))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filter %q/%q kept %q, want %q", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestGroupProducts(t *testing.T) {
+	records := []*Record{
+		{URL: "https://ci.example.com", Favicon: "81586312", Tech: []string{"Jetty"}},
+		{URL: "http://www.example.com", Tech: []string{"Nginx:1.19"}},
+		{URL: "https://www.example.com", Tech: []string{"Nginx:1.19"}},
+		{URL: "https://api.example.com", Tech: []string{"Nginx", "Express"}},
+	}
+	TagProducts(records, FaviconTable{"81586312": "Jenkins"})
+
+	want := []ProductHosts{
+		{Product: "Nginx", Hosts: []string{"www.example.com", "api.example.com"}},
+		{Product: "Jenkins", Hosts: []string{"ci.example.com"}},
+		{Product: "Jetty", Hosts: []string{"ci.example.com"}},
+		{Product: "Express", Hosts: []string{"api.example.com"}},
+	}
+	if got := GroupProducts(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupProducts() = %+v, want %+v", got, want)
+	}
+}
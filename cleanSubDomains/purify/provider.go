@@ -0,0 +1,202 @@
+package purify
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed providers.txt
+var builtinProviders string
+
+// Provider modes of the purified output.
+const (
+	ProviderTag      = "tag"
+	ProviderCollapse = "collapse"
+	ProviderSeparate = "separate"
+)
+
+// Provider is a CDN, WAF or cloud provider a record is hosted at.
+type Provider struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// fronted checks if the provider is a CDN or WAF in front of the origin.
+func (p *Provider) fronted() bool {
+	return p != nil && (p.Kind == "cdn" || p.Kind == "waf")
+}
+
+// providerName returns the name of the provider of the record.
+func providerName(r *Record) string {
+	if r.provider == nil {
+		return ""
+	}
+	return r.provider.Name
+}
+
+// providerKind returns the kind of the provider of the record.
+func providerKind(r *Record) string {
+	if r.provider == nil {
+		return ""
+	}
+	return r.provider.Kind
+}
+
+type providerNetwork struct {
+	network  *net.IPNet
+	provider *Provider
+}
+
+type providerSuffix struct {
+	suffix   string
+	provider *Provider
+}
+
+// ProviderTable contains the IP ranges and CNAME suffixes of the providers.
+type ProviderTable struct {
+	networks []providerNetwork
+	suffixes []providerSuffix
+	byName   map[string]*Provider
+}
+
+// NewProviderTable returns an empty provider table.
+func NewProviderTable() *ProviderTable {
+	return &ProviderTable{byName: make(map[string]*Provider)}
+}
+
+// LoadFile adds the entries of the table file.
+func (t *ProviderTable) LoadFile(tableFile string) error {
+	file, err := os.Open(tableFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return t.Load(file, tableFile)
+}
+
+// LoadBuiltin adds the entries of the built-in table.
+func (t *ProviderTable) LoadBuiltin() error {
+	return t.Load(strings.NewReader(builtinProviders), "providers.txt")
+}
+
+// Load adds the entries of a table. Every line contains the provider name,
+// the kind and any number of CIDR ranges or CNAME suffixes. Empty lines and
+// lines starting with # are ignored.
+func (t *ProviderTable) Load(reader io.Reader, source string) error {
+	sc := bufio.NewScanner(reader)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 3 {
+			return fmt.Errorf("%s:%d: expected provider, kind and ranges or CNAME suffixes", source, line)
+		}
+		kind := strings.ToLower(fields[1])
+		if kind != "cdn" && kind != "waf" && kind != "cloud" {
+			return fmt.Errorf("%s:%d: unknown provider kind %q", source, line, fields[1])
+		}
+		p, ok := t.byName[fields[0]+"|"+kind]
+		if !ok {
+			p = &Provider{Name: fields[0], Kind: kind}
+			t.byName[fields[0]+"|"+kind] = p
+		}
+		for _, value := range fields[2:] {
+			if strings.Contains(value, "/") {
+				_, network, err := net.ParseCIDR(value)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %v", source, line, err)
+				}
+				t.networks = append(t.networks, providerNetwork{network: network, provider: p})
+			} else {
+				suffix := strings.Trim(strings.ToLower(value), ".")
+				t.suffixes = append(t.suffixes, providerSuffix{suffix: suffix, provider: p})
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// lookup returns the provider of the record. The addresses are checked first,
+// then the CNAMEs and at last the CDN detected by httpx (-cdn).
+func (t *ProviderTable) lookup(r *Record) *Provider {
+	for _, address := range recordIPs(r) {
+		for _, pn := range t.networks {
+			if pn.network.Contains(address) {
+				return pn.provider
+			}
+		}
+	}
+	for _, cname := range r.CNAME {
+		if p := t.lookupCNAME(cname); p != nil {
+			return p
+		}
+	}
+	if r.CDNName != "" {
+		kind := strings.ToLower(r.CDNType)
+		if kind == "" {
+			kind = "cdn"
+		}
+		name := strings.ToLower(r.CDNName)
+		p, ok := t.byName[name+"|"+kind]
+		if !ok {
+			p = &Provider{Name: name, Kind: kind}
+			t.byName[name+"|"+kind] = p
+		}
+		return p
+	}
+	return nil
+}
+
+// lookupCNAME returns the provider whose CNAME suffix matches the name.
+func (t *ProviderTable) lookupCNAME(name string) *Provider {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, ps := range t.suffixes {
+		if name == ps.suffix || strings.HasSuffix(name, "."+ps.suffix) {
+			return ps.provider
+		}
+	}
+	return nil
+}
+
+// TagProviders tags every record with its provider.
+func TagProviders(records []*Record, table *ProviderTable) {
+	counts := make(map[string]int)
+	for _, r := range records {
+		r.provider = table.lookup(r)
+		if r.provider != nil {
+			counts[r.provider.Name]++
+		}
+	}
+
+	var names []string
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Infof("%d records are hosted at %s", counts[name], name)
+	}
+}
+
+// SplitFronted splits the records into those behind a CDN or WAF and those
+// whose origin is exposed.
+func SplitFronted(records []*Record) ([]*Record, []*Record) {
+	var fronted, origin []*Record
+	for _, r := range records {
+		if r.provider.fronted() {
+			fronted = append(fronted, r)
+		} else {
+			origin = append(origin, r)
+		}
+	}
+	return fronted, origin
+}
@@ -0,0 +1,109 @@
+package purify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProviderLookup(t *testing.T) {
+	table := NewProviderTable()
+	if err := table.LoadBuiltin(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		record *Record
+		want   string
+	}{
+		{&Record{A: []string{"104.16.1.1"}}, "cloudflare|cdn"},
+		{&Record{A: []string{"2606:4700::1"}}, "cloudflare|cdn"},
+		{&Record{Host: "151.101.1.1"}, "fastly|cdn"},
+		{&Record{CNAME: []string{"www.example.com.edgekey.net."}}, "akamai|cdn"},
+		{&Record{CNAME: []string{"Example.CDN.Cloudflare.NET"}}, "cloudflare|cdn"},
+		{&Record{CNAME: []string{"notcloudflare.net"}}, ""},
+		{&Record{CDNName: "Imperva", CDNType: "waf"}, "imperva|waf"},
+		{&Record{CDNName: "Example"}, "example|cdn"},
+		{&Record{A: []string{"192.0.2.1"}}, ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if p := table.lookup(tt.record); p != nil {
+			got = p.Name + "|" + p.Kind
+		}
+		if got != tt.want {
+			t.Errorf("lookup(%+v) = %q, want %q", tt.record, got, tt.want)
+		}
+	}
+}
+
+func TestProviderTablePrecedence(t *testing.T) {
+	user := `# internal load balancers
+corp-lb  waf  104.16.0.0/16
+corp-lb  waf  lb.example.com
+`
+	table := NewProviderTable()
+	if err := table.Load(strings.NewReader(user), "user.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.LoadBuiltin(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*Record{{A: []string{"104.16.1.1"}}, {CNAME: []string{"app.lb.example.com"}}} {
+		if p := table.lookup(r); p == nil || p.Name != "corp-lb" {
+			t.Errorf("lookup(%+v) = %v, want corp-lb of the user table", r, p)
+		}
+	}
+	if p := table.lookup(&Record{A: []string{"104.18.1.1"}}); p == nil || p.Name != "cloudflare" {
+		t.Errorf("lookup of 104.18.1.1 = %v, want cloudflare of the built-in table", p)
+	}
+}
+
+func TestProviderTableInvalid(t *testing.T) {
+	for _, table := range []string{
+		"cloudflare cdn",
+		"cloudflare proxy 104.16.0.0/13",
+		"cloudflare cdn 104.16.0.0/33",
+	} {
+		if err := NewProviderTable().Load(strings.NewReader(table), "user.txt"); err == nil {
+			t.Errorf("table %q returned no error", table)
+		}
+	}
+}
+
+func TestProviderModes(t *testing.T) {
+	table := NewProviderTable()
+	if err := table.LoadBuiltin(); err != nil {
+		t.Fatal(err)
+	}
+	records := []*Record{
+		{URL: "https://a.example.com", StatusCode: 403, Title: "Attention Required! | Cloudflare", A: []string{"104.16.1.1"}},
+		{URL: "https://b.example.com", StatusCode: 200, Title: "Shop", A: []string{"192.0.2.10"}},
+		{URL: "https://c.example.com", StatusCode: 403, Title: "ATTENTION REQUIRED! | CLOUDFLARE", A: []string{"104.17.1.1"}},
+		{URL: "https://d.example.com", StatusCode: 403, Title: "Attention Required! | Cloudflare", A: []string{"151.101.1.1"}},
+	}
+	TagProviders(records, table)
+
+	// separate writes the hosts behind a CDN or WAF to their own file
+	fronted, origin := SplitFronted(records)
+	if len(fronted) != 3 || len(origin) != 1 || origin[0] != records[1] {
+		t.Fatalf("split into %d fronted and %d origin records, want 3 and 1", len(fronted), len(origin))
+	}
+
+	// collapse groups the hosts behind a CDN or WAF by provider, status and
+	// title
+	key, err := ParseGroupKey("provider,status_code,title:lower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := key.Group(fronted)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if len(groups[0].Records) != 2 || groups[0].Records[1] != records[2] {
+		t.Errorf("first group = %v, want a and c", groups[0].Records)
+	}
+	if groups[1].Key != "fastly|403|attention required! | cloudflare" {
+		t.Errorf("second group key = %q", groups[1].Key)
+	}
+}
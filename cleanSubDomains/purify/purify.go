@@ -0,0 +1,60 @@
+// Package purify removes duplicate responses from the (pd) httpx JSON output,
+// so that only a few representatives of every group of hosts returning the
+// same response are kept.
+//
+// Records are read by a Reader, grouped by a Grouper, ranked per group by a
+// Selector and the kept records are written by a Writer. The Purifier
+// combines a Grouper and a Selector. The other parts of the cleanSubDomains
+// pipeline, like scope rules, wildcard zones, services, providers and
+// products, work on the same records and can be used before grouping.
+package purify
+
+import "io"
+
+// Reader reads the records of a httpx output and calls fn for every record.
+type Reader interface {
+	Read(fn func(r *Record)) (ReadStats, error)
+}
+
+// Grouper groups records whose responses are duplicates of each other.
+type Grouper interface {
+	Group(records []*Record) []*Group
+}
+
+// Selector ranks the records of a group. The first records are kept as
+// representatives of the group.
+type Selector interface {
+	Rank(g *Group) ([]*Record, map[*Record]Selection)
+}
+
+// Writer writes the records and returns the amount of entries written.
+type Writer interface {
+	Write(w io.Writer, records []*Record) (int, error)
+}
+
+// Purifier keeps at most DupCount records per group of duplicates.
+type Purifier struct {
+	Grouper  Grouper
+	Selector Selector
+	DupCount int
+}
+
+// Purify groups the records and returns the kept records in input order
+// together with the groups.
+func (p *Purifier) Purify(records []*Record) ([]*Record, []*Group) {
+	groups := p.Grouper.Group(records)
+	LimitGroups(groups, p.DupCount, p.Selector)
+	return KeptRecords(records, groups), groups
+}
+
+// ReadAll reads all records. Records of hosts which couldn't be probed are
+// skipped, but counted in the statistics.
+func ReadAll(reader Reader) ([]*Record, ReadStats, error) {
+	var records []*Record
+	stats, err := reader.Read(func(r *Record) {
+		if !r.Failed {
+			records = append(records, r)
+		}
+	})
+	return records, stats, err
+}
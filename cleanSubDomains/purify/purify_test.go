@@ -0,0 +1,204 @@
+package purify
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// fixtures are the options used to purify the httpx outputs in testdata.
+var fixtures = map[string]struct {
+	key       string
+	policy    string
+	dupCount  int
+	wildcards int
+	services  bool
+}{
+	"duplicates": {key: "default", policy: "preferred", dupCount: 1},
+	"wildcards":  {key: "default", policy: "first", dupCount: 10, wildcards: 3},
+	"catchall":   {key: "loose", policy: "preferred", dupCount: 1},
+	"redirects":  {key: "default", policy: "first", dupCount: 10, services: true},
+}
+
+// result is the purification output pinned by the golden files.
+type result struct {
+	Kept     []string   `json:"kept"`
+	Report   *Report    `json:"report"`
+	Services []*Service `json:"services,omitempty"`
+}
+
+func TestPurifyGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < len(fixtures) {
+		t.Fatalf("found %d fixtures, want %d", len(files), len(fixtures))
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".jsonl")
+		t.Run(name, func(t *testing.T) {
+			options, ok := fixtures[name]
+			if !ok {
+				t.Fatalf("no options for fixture %s", file)
+			}
+			records := readFixture(t, file)
+			inputCount := len(records)
+
+			var res result
+			if options.services {
+				records, res.Services = DedupeServices(records)
+			}
+			var zones []*WildcardZone
+			if options.wildcards > 0 {
+				records, zones = DetectWildcards(records, options.wildcards)
+			}
+			key, err := ParseGroupKey(options.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			policy, err := ParseSelectionPolicy(options.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Purifier{Grouper: key, Selector: policy, DupCount: options.dupCount}
+			kept, groups := p.Purify(records)
+			for _, r := range kept {
+				res.Kept = append(res.Kept, r.URL)
+			}
+			res.Report = CreateReport(inputCount, kept, groups, zones, nil)
+
+			got, err := json.MarshalIndent(res, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from the purification output:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// reverseSelector keeps the last records of a group.
+type reverseSelector struct{}
+
+func (reverseSelector) Rank(g *Group) ([]*Record, map[*Record]Selection) {
+	var ranked []*Record
+	for i := len(g.Records) - 1; i >= 0; i-- {
+		ranked = append(ranked, g.Records[i])
+	}
+	return ranked, nil
+}
+
+func TestPurifierSelector(t *testing.T) {
+	records := readFixture(t, filepath.Join("testdata", "duplicates.jsonl"))
+	key, err := ParseGroupKey("status_code,title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Purifier{Grouper: key, Selector: reverseSelector{}, DupCount: 1}
+	kept, groups := p.Purify(records)
+
+	var urls []string
+	for _, r := range kept {
+		urls = append(urls, r.URL)
+	}
+	want := "https://dev.example.com:8443,https://auth.example.com,https://legacy.example.com," +
+		"https://api.example.com,https://cdn.example.com"
+	if got := strings.Join(urls, ","); got != want {
+		t.Errorf("kept %s, want %s", got, want)
+	}
+	if len(groups) != 5 {
+		t.Errorf("got %d groups, want 5", len(groups))
+	}
+}
+
+func readFixture(t *testing.T, file string) []*Record {
+	t.Helper()
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, _, err := ReadAll(NewReader(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestPurifierNoDuplicates(t *testing.T) {
+	records := readFixture(t, filepath.Join("testdata", "duplicates.jsonl"))
+	key, err := ParseGroupKey("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Purifier{Grouper: key, Selector: reverseSelector{}, DupCount: 0}
+	kept, groups := p.Purify(records)
+	if len(kept) != 0 {
+		t.Errorf("kept %d records, want 0", len(kept))
+	}
+	rep := CreateReport(len(records), kept, groups, nil, nil)
+	if rep.Summary.DroppedDuplicates != len(records) {
+		t.Errorf("dropped %d duplicates, want %d", rep.Summary.DroppedDuplicates, len(records))
+	}
+}
+
+func TestCreateReport(t *testing.T) {
+	records := []*Record{
+		{URL: "https://a.example.com", Input: "a.example.com", StatusCode: 200, Title: "Shop"},
+		{URL: "https://b.example.com", Input: "b.example.com", StatusCode: 200, Title: "Shop"},
+		{URL: "https://c.example.com", Input: "c.example.com", StatusCode: 404, Title: "Not found"},
+	}
+	key, err := ParseGroupKey("status_code,title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := ParseSelectionPolicy("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		dupCount int
+		reason   string
+	}{
+		{1, "duplicate of https://a.example.com (200|Shop)"},
+		{0, "duplicate (200|Shop)"},
+	} {
+		p := &Purifier{Grouper: key, Selector: policy, DupCount: tt.dupCount}
+		kept, groups := p.Purify(records)
+		rep := CreateReport(len(records), kept, groups, nil, nil)
+
+		// Unique hosts are reported as groups of their own
+		if rep.Summary.Groups != 2 || len(rep.Groups) != 2 {
+			t.Fatalf("dupCount %d: got %d groups, want 2", tt.dupCount, len(rep.Groups))
+		}
+		unique := rep.Groups[1]
+		if unique.Size != 1 || len(unique.Kept) != tt.dupCount || len(unique.Dropped) != 1-tt.dupCount {
+			t.Errorf("dupCount %d: unique group = %+v", tt.dupCount, unique)
+		}
+		if dropped := rep.Groups[0].Dropped; len(dropped) == 0 || dropped[len(dropped)-1].Reason != tt.reason {
+			t.Errorf("dupCount %d: dropped = %+v, want reason %q", tt.dupCount, dropped, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package purify
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// scopeRule is a single in-scope or out-of-scope rule. A rule is either an IP
+// address, a CIDR range, a regular expression (prefixed with re:), a glob
+// pattern or a host name, which also matches all of its subdomains.
+type scopeRule struct {
+	text    string
+	source  string
+	ip      net.IP
+	network *net.IPNet
+	regex   *regexp.Regexp
+	glob    string
+	domain  string
+}
+
+// ScopeRuleReport lists the records removed by a rule.
+type ScopeRuleReport struct {
+	Rule    string   `json:"rule"`
+	Source  string   `json:"source"`
+	Removed []string `json:"removed"`
+}
+
+// Scope contains the include and exclude rules. If include rules exist, a
+// record must match at least one of them. Records matching an exclude rule
+// are always removed.
+type Scope struct {
+	include []*scopeRule
+	exclude []*scopeRule
+}
+
+// notInScope is the pseudo rule reported for records which didn't match any
+// include rule.
+var notInScope = &scopeRule{text: "not matching any include rule", source: "scope"}
+
+func parseScopeRule(text string, source string) (*scopeRule, error) {
+	rule := &scopeRule{text: text, source: source}
+	switch {
+	case strings.HasPrefix(text, "re:"):
+		regex, err := regexp.Compile(strings.TrimPrefix(text, "re:"))
+		if err != nil {
+			return nil, err
+		}
+		rule.regex = regex
+	case strings.Contains(text, "/"):
+		_, network, err := net.ParseCIDR(text)
+		if err != nil {
+			return nil, err
+		}
+		rule.network = network
+	case net.ParseIP(text) != nil:
+		rule.ip = net.ParseIP(text)
+	case strings.ContainsAny(text, "*?["):
+		if _, err := path.Match(text, ""); err != nil {
+			return nil, err
+		}
+		rule.glob = strings.ToLower(text)
+	default:
+		rule.domain = strings.TrimSuffix(strings.ToLower(text), ".")
+	}
+	return rule, nil
+}
+
+// AddRules adds the comma separated rules to the scope.
+func (s *Scope) AddRules(rules string, source string, exclude bool) error {
+	for _, text := range strings.Split(rules, ",") {
+		if err := s.addRule(text, source, exclude); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var asnRegex = regexp.MustCompile(`^(?i)AS[0-9]+$`)
+
+// AddRuleFile adds the rules from the file. Multiple rules per line can be
+// separated by white space or commas. Empty lines and lines starting with #
+// are ignored, as well as AS numbers, so prefix lists of an ASN (e.g.
+// "AS13335 104.16.0.0/13") can be used directly.
+func (s *Scope) AddRuleFile(ruleFile string, exclude bool) error {
+	file, err := os.Open(ruleFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sc := bufio.NewScanner(file)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, field := range fields {
+			if asnRegex.MatchString(field) {
+				continue
+			}
+			if err := s.addRule(field, fmt.Sprintf("%s:%d", ruleFile, line), exclude); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
+
+func (s *Scope) addRule(text string, source string, exclude bool) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	rule, err := parseScopeRule(text, source)
+	if err != nil {
+		return fmt.Errorf("invalid scope rule %q (%s): %v", text, source, err)
+	}
+	if exclude {
+		s.exclude = append(s.exclude, rule)
+	} else {
+		s.include = append(s.include, rule)
+	}
+	return nil
+}
+
+// Empty checks if the scope has no rules.
+func (s *Scope) Empty() bool {
+	return len(s.include) == 0 && len(s.exclude) == 0
+}
+
+// match returns the rule which removes the record, nil if the record is in
+// scope.
+func (s *Scope) match(r *Record) *scopeRule {
+	host := HostName(r)
+	addresses := recordIPs(r)
+
+	if len(s.include) > 0 {
+		included := false
+		for _, rule := range s.include {
+			if rule.matches(host, addresses) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return notInScope
+		}
+	}
+	for _, rule := range s.exclude {
+		if rule.matches(host, addresses) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (rule *scopeRule) matches(host string, addresses []net.IP) bool {
+	switch {
+	case rule.regex != nil:
+		return rule.regex.MatchString(host)
+	case rule.network != nil:
+		for _, address := range addresses {
+			if rule.network.Contains(address) {
+				return true
+			}
+		}
+	case rule.ip != nil:
+		for _, address := range addresses {
+			if rule.ip.Equal(address) {
+				return true
+			}
+		}
+		return rule.ip.Equal(net.ParseIP(host))
+	case rule.glob != "":
+		match, _ := path.Match(rule.glob, host)
+		return match
+	default:
+		return host == rule.domain || strings.HasSuffix(host, "."+rule.domain)
+	}
+	return false
+}
+
+// FilterScope removes all records which are not in scope. The returned
+// reports list the removed records per rule.
+func FilterScope(records []*Record, s *Scope) ([]*Record, []*ScopeRuleReport) {
+	var remaining []*Record
+	var reports []*ScopeRuleReport
+	byRule := make(map[*scopeRule]*ScopeRuleReport)
+
+	for _, r := range records {
+		rule := s.match(r)
+		if rule == nil {
+			remaining = append(remaining, r)
+			continue
+		}
+		report, ok := byRule[rule]
+		if !ok {
+			report = &ScopeRuleReport{Rule: rule.text, Source: rule.source}
+			byRule[rule] = report
+			reports = append(reports, report)
+		}
+		report.Removed = append(report.Removed, r.URL)
+	}
+
+	for _, report := range reports {
+		log.Infof("Scope rule %q (%s) removed %d records", report.Rule, report.Source, len(report.Removed))
+	}
+	return remaining, reports
+}
+
+// recordIPs returns the IP addresses of the record.
+func recordIPs(r *Record) []net.IP {
+	var addresses []net.IP
+	for _, a := range r.A {
+		if ip := net.ParseIP(a); ip != nil {
+			addresses = append(addresses, ip)
+		}
+	}
+	if ip := net.ParseIP(r.Host); ip != nil {
+		addresses = append(addresses, ip)
+	}
+	return addresses
+}
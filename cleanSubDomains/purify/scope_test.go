@@ -0,0 +1,37 @@
+package purify
+
+import "testing"
+
+func TestFilterScope(t *testing.T) {
+	s := &Scope{}
+	if err := s.AddRules("example.com,re:^api[0-9]*\\.example\\.org$,192.0.2.0/24", "-h", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddRules("*.dev.example.com,192.0.2.66", "-x", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddRules("re:(", "-h", false); err == nil {
+		t.Error("invalid regular expression returned no error")
+	}
+
+	tests := []struct {
+		record Record
+		in     bool
+	}{
+		{Record{URL: "https://example.com"}, true},
+		{Record{URL: "https://www.example.com"}, true},
+		{Record{URL: "https://notexample.com"}, false},
+		{Record{URL: "https://api2.example.org"}, true},
+		{Record{URL: "https://www.example.org"}, false},
+		{Record{URL: "https://www.example.org", A: []string{"192.0.2.10"}}, true},
+		{Record{URL: "https://app.dev.example.com"}, false},
+		{Record{URL: "https://www.example.com", A: []string{"192.0.2.66"}}, false},
+	}
+	for _, tt := range tests {
+		r := tt.record
+		remaining, _ := FilterScope([]*Record{&r}, s)
+		if in := len(remaining) == 1; in != tt.in {
+			t.Errorf("%s (%v) in scope = %v, want %v", r.URL, r.A, in, tt.in)
+		}
+	}
+}
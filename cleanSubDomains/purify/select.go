@@ -0,0 +1,192 @@
+package purify
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// selectionPresets are the predefined representative selection policies.
+var selectionPresets = map[string]string{
+	"first":     "first",
+	"preferred": "https:4,port:3,cert:2,shallow:2,short:1,noncdn:1",
+}
+
+// strategy scores a record of a group between 0 and 1. The higher the score,
+// the more the record is preferred as representative of the group.
+type strategy struct {
+	description string
+	score       func(r *Record, g *Group) float64
+}
+
+// strategies are the built-in strategies for selecting representatives.
+var strategies = map[string]strategy{
+	"first": {"first seen", func(r *Record, g *Group) float64 {
+		for i, other := range g.Records {
+			if other == r {
+				return 1 - float64(i)/float64(len(g.Records))
+			}
+		}
+		return 0
+	}},
+	"https": {"https", func(r *Record, g *Group) float64 {
+		return boolScore(recordScheme(r) == "https")
+	}},
+	"port": {"default port", func(r *Record, g *Group) float64 {
+		p := recordPort(r)
+		return boolScore(p == "" || p == "443" || p == "80")
+	}},
+	"short": {"short name", func(r *Record, g *Group) float64 {
+		return ratio(g, r, func(r *Record) int { return len(HostName(r)) })
+	}},
+	"shallow": {"near apex", func(r *Record, g *Group) float64 {
+		return ratio(g, r, func(r *Record) int { return strings.Count(HostName(r), ".") + 1 })
+	}},
+	"noncdn": {"not behind CDN", func(r *Record, g *Group) float64 {
+		return boolScore(!r.CDN)
+	}},
+	"cert": {"in certificate", func(r *Record, g *Group) float64 {
+		return boolScore(inCertificate(r))
+	}},
+}
+
+type weightedStrategy struct {
+	name   string
+	weight float64
+	strategy
+}
+
+// SelectionPolicy chooses the records kept for a group by the weighted sum of
+// the scores of its strategies. Records with the same score are kept in the
+// order of the input.
+type SelectionPolicy struct {
+	definition string
+	strategies []weightedStrategy
+}
+
+// Selection is the score of a record and why it got it.
+type Selection struct {
+	Score  float64
+	Reason string
+}
+
+// ParseSelectionPolicy parses a preset name or a comma separated list of
+// strategies with optional weights, for example "https:3,shallow:2,first".
+func ParseSelectionPolicy(definition string) (*SelectionPolicy, error) {
+	if preset, ok := selectionPresets[definition]; ok {
+		definition = preset
+	}
+	policy := &SelectionPolicy{definition: definition}
+
+	for _, item := range strings.Split(definition, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name := item
+		weight := 1.0
+		if colon := strings.Index(item, ":"); colon >= 0 {
+			name = item[:colon]
+			w, err := strconv.ParseFloat(item[colon+1:], 64)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight of selection strategy %q", item)
+			}
+			weight = w
+		}
+		s, ok := strategies[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown selection strategy %q, known strategies are %s", name,
+				strings.Join(StrategyNames(), ","))
+		}
+		policy.strategies = append(policy.strategies, weightedStrategy{name: name, weight: weight, strategy: s})
+	}
+	return policy, nil
+}
+
+// StrategyNames returns the sorted names of the selection strategies.
+func StrategyNames() []string {
+	var names []string
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rank returns the records of the group ordered by their score, together with
+// the score and the reason for every record.
+func (p *SelectionPolicy) Rank(g *Group) ([]*Record, map[*Record]Selection) {
+	selections := make(map[*Record]Selection)
+	for _, r := range g.Records {
+		var s Selection
+		var reasons []string
+		for _, ws := range p.strategies {
+			score := ws.weight * ws.score(r, g)
+			if score > 0 {
+				s.Score += score
+				reasons = append(reasons, fmt.Sprintf("%s %s", ws.description, formatScore(score)))
+			}
+		}
+		s.Reason = strings.Join(reasons, ", ")
+		selections[r] = s
+	}
+
+	ranked := append([]*Record(nil), g.Records...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return selections[ranked[i]].Score > selections[ranked[j]].Score
+	})
+	return ranked, selections
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ratio returns the smallest value of the group divided by the value of the
+// record, so the record with the smallest value gets 1.
+func ratio(g *Group, r *Record, value func(r *Record) int) float64 {
+	min := 0
+	for _, other := range g.Records {
+		if v := value(other); v > 0 && (min == 0 || v < min) {
+			min = v
+		}
+	}
+	if v := value(r); v > 0 {
+		return float64(min) / float64(v)
+	}
+	return 0
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(math.Round(score*100)/100, 'f', -1, 64)
+}
+
+// recordScheme returns the scheme of the record, taken from the URL if httpx
+// didn't output it.
+func recordScheme(r *Record) string {
+	if r.Scheme != "" {
+		return strings.ToLower(r.Scheme)
+	}
+	if colon := strings.Index(r.URL, "://"); colon > 0 {
+		return strings.ToLower(r.URL[:colon])
+	}
+	return ""
+}
+
+// recordPort returns the port of the record, empty if the URL uses the
+// default port of the scheme and httpx didn't output it.
+func recordPort(r *Record) string {
+	if r.Port != "" {
+		return string(r.Port)
+	}
+	if u, err := url.Parse(r.URL); err == nil {
+		return u.Port()
+	}
+	return ""
+}
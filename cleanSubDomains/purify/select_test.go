@@ -0,0 +1,51 @@
+package purify
+
+import "testing"
+
+func TestParseSelectionPolicy(t *testing.T) {
+	for _, definition := range []string{"first", "preferred", "https:2,short", " cert , port:0.5"} {
+		if _, err := ParseSelectionPolicy(definition); err != nil {
+			t.Errorf("ParseSelectionPolicy(%q) = %v", definition, err)
+		}
+	}
+	for _, definition := range []string{"nope", "https:x", "https:-1"} {
+		if _, err := ParseSelectionPolicy(definition); err == nil {
+			t.Errorf("ParseSelectionPolicy(%q) returned no error", definition)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	g := &Group{Records: []*Record{
+		{URL: "http://a.b.example.com:8080", CDN: true},
+		{URL: "http://www.example.com"},
+		{URL: "https://deep.dev.example.com"},
+		{URL: "https://example.com"},
+	}}
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{"first", []string{"http://a.b.example.com:8080", "http://www.example.com",
+			"https://deep.dev.example.com", "https://example.com"}},
+		{"preferred", []string{"https://example.com", "https://deep.dev.example.com",
+			"http://www.example.com", "http://a.b.example.com:8080"}},
+		{"https", []string{"https://deep.dev.example.com", "https://example.com",
+			"http://a.b.example.com:8080", "http://www.example.com"}},
+	}
+	for _, tt := range tests {
+		policy, err := ParseSelectionPolicy(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ranked, selections := policy.Rank(g)
+		for i, r := range ranked {
+			if r.URL != tt.want[i] {
+				t.Errorf("%s: rank %d = %s, want %s", tt.policy, i+1, r.URL, tt.want[i])
+			}
+		}
+		if len(selections) != len(g.Records) {
+			t.Errorf("%s: got %d selections, want %d", tt.policy, len(selections), len(g.Records))
+		}
+	}
+}
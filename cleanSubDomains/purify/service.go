@@ -0,0 +1,219 @@
+package purify
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxRedirects is the maximum amount of recorded redirects followed to find
+// the target of a record.
+const maxRedirects = 10
+
+// ServiceEntry is an alternate URL of a service.
+type ServiceEntry struct {
+	URL         string `json:"url"`
+	RedirectsTo string `json:"redirects_to,omitempty"`
+}
+
+// Service is a single web application of a host which is often reachable by
+// several URLs, e.g. http://host redirecting to https://host and the same
+// application on port 8443. Only the canonical URL is kept, the others are
+// listed as alternate entry points.
+type Service struct {
+	Host       string         `json:"host"`
+	Target     string         `json:"target"`
+	Canonical  string         `json:"canonical"`
+	Alternates []ServiceEntry `json:"alternates,omitempty"`
+}
+
+// canonicalURL normalises a URL for comparison: scheme and host are lower
+// case, default ports and fragments are removed and an empty path becomes /.
+func canonicalURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if p := u.Port(); p != "" && !(u.Scheme == "http" && p == "80") && !(u.Scheme == "https" && p == "443") {
+		host += ":" + p
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	u.Fragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// redirect returns the canonical URL the record redirects to, empty if it
+// doesn't redirect. The final URL of httpx (-fr) is preferred over the
+// location header, relative locations are resolved against the URL.
+func redirect(r *Record) string {
+	target := r.FinalURL
+	if target == "" {
+		target = r.Location
+	}
+	if target == "" {
+		return ""
+	}
+	if base, err := url.Parse(r.URL); err == nil {
+		if ref, err := url.Parse(target); err == nil {
+			target = base.ResolveReference(ref).String()
+		}
+	}
+	if target = canonicalURL(target); target == canonicalURL(r.URL) {
+		return ""
+	}
+	return target
+}
+
+// redirectTarget follows the redirects of the record through the records
+// probed for the redirect targets and returns the URL it ends at. For
+// redirect loops the smallest URL of the loop is returned, so all records of
+// the loop end at the same target.
+func redirectTarget(r *Record, byURL map[string]*Record) string {
+	path := []string{canonicalURL(r.URL)}
+	for i := 0; i < maxRedirects; i++ {
+		next := redirect(r)
+		if next == "" {
+			break
+		}
+		for j, seen := range path {
+			if seen == next {
+				loop := append([]string(nil), path[j:]...)
+				sort.Strings(loop)
+				return loop[0]
+			}
+		}
+		path = append(path, next)
+		if r = byURL[next]; r == nil {
+			break
+		}
+	}
+	return path[len(path)-1]
+}
+
+// serviceResponse describes the response of a service on the alternate ports
+// and schemes of the same host. Without a body hash it is empty, since status
+// code, length and title alone are shared by many different applications
+// (e.g. default pages or login forms).
+func serviceResponse(r *Record) string {
+	if r.Hash.BodySHA256 == "" {
+		return ""
+	}
+	return fmt.Sprintf("status=%d length=%d title=%q body=%s", r.StatusCode, r.ContentLength, r.Title,
+		r.Hash.BodySHA256)
+}
+
+// DedupeServices merges the records of a host which end at the same target,
+// following the recorded redirects, or which return the same response on
+// another port or scheme (only if httpx reported the body hash). Redirects to other hosts don't merge records, since
+// every host should remain in the output. For every service only the
+// canonical record is returned, preferring records which don't redirect,
+// https and the default ports.
+func DedupeServices(records []*Record) ([]*Record, []*Service) {
+	byURL := make(map[string]*Record)
+	for _, r := range records {
+		if u := canonicalURL(r.URL); byURL[u] == nil {
+			byURL[u] = r
+		}
+	}
+
+	// Records are merged with a union-find, so the order of the input
+	// doesn't matter.
+	parent := make([]int, len(records))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i int, j int) {
+		if i, j = find(i), find(j); i != j {
+			if j < i {
+				i, j = j, i
+			}
+			parent[j] = i
+		}
+	}
+
+	first := make(map[string]int)
+	for i, r := range records {
+		keys := []string{HostName(r) + "|target|" + redirectTarget(r, byURL)}
+		if response := serviceResponse(r); response != "" && redirect(r) == "" {
+			keys = append(keys, HostName(r)+"|response|"+response)
+		}
+		for _, key := range keys {
+			if j, ok := first[key]; ok {
+				union(i, j)
+			} else {
+				first[key] = i
+			}
+		}
+	}
+
+	var services []*Service
+	var groups [][]*Record
+	byRoot := make(map[int]int)
+	for i, r := range records {
+		root := find(i)
+		n, ok := byRoot[root]
+		if !ok {
+			n = len(services)
+			byRoot[root] = n
+			services = append(services, &Service{Host: HostName(r)})
+			groups = append(groups, nil)
+		}
+		groups[n] = append(groups[n], r)
+	}
+
+	var remaining []*Record
+	kept := make(map[*Record]bool)
+	alternates := 0
+	for n, s := range services {
+		ranked := append([]*Record(nil), groups[n]...)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return serviceScore(ranked[i]) > serviceScore(ranked[j])
+		})
+		s.Canonical = ranked[0].URL
+		s.Target = redirectTarget(ranked[0], byURL)
+		for _, r := range ranked[1:] {
+			s.Alternates = append(s.Alternates, ServiceEntry{URL: r.URL, RedirectsTo: redirect(r)})
+		}
+		alternates += len(s.Alternates)
+		kept[ranked[0]] = true
+	}
+	for _, r := range records {
+		if kept[r] {
+			remaining = append(remaining, r)
+		}
+	}
+	log.Infof("Merged %d alternate entry points into %d services", alternates, len(services))
+	return remaining, services
+}
+
+// serviceScore ranks the records of a service as canonical URL.
+func serviceScore(r *Record) int {
+	score := 0
+	if redirect(r) == "" {
+		score += 4
+	}
+	if recordScheme(r) == "https" {
+		score += 2
+	}
+	if p := recordPort(r); p == "" || p == "443" || p == "80" {
+		score++
+	}
+	return score
+}
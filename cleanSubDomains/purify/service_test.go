@@ -0,0 +1,98 @@
+package purify
+
+import "testing"
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"HTTPS://WWW.Example.com", "https://www.example.com/"},
+		{"https://www.example.com:443/a#top", "https://www.example.com/a"},
+		{"http://www.example.com:80", "http://www.example.com/"},
+		{"http://www.example.com:8080/?q=1", "http://www.example.com:8080/?q=1"},
+		{"http://[2001:db8::1]:80/", "http://[2001:db8::1]/"},
+		{"www.example.com", "www.example.com"},
+	}
+	for _, tt := range tests {
+		if got := canonicalURL(tt.url); got != tt.want {
+			t.Errorf("canonicalURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	tests := []struct {
+		record Record
+		want   string
+	}{
+		{Record{URL: "http://a.example.com"}, ""},
+		{Record{URL: "http://a.example.com", Location: "https://a.example.com/"}, "https://a.example.com/"},
+		{Record{URL: "http://a.example.com/x/", Location: "../login"}, "http://a.example.com/login"},
+		{Record{URL: "http://a.example.com", Location: "/", FinalURL: "https://sso.example.com"},
+			"https://sso.example.com/"},
+		{Record{URL: "https://a.example.com", Location: "https://A.example.com:443/"}, ""},
+	}
+	for _, tt := range tests {
+		if got := redirect(&tt.record); got != tt.want {
+			t.Errorf("redirect(%s) = %q, want %q", tt.record.URL, got, tt.want)
+		}
+	}
+}
+
+func TestDedupeServices(t *testing.T) {
+	records := []*Record{
+		withBody(&Record{URL: "https://a.example.com:8443", StatusCode: 200, ContentLength: 10, Title: "A"}, "aa"),
+		{URL: "http://a.example.com", StatusCode: 301, Location: "https://a.example.com/"},
+		withBody(&Record{URL: "https://a.example.com", StatusCode: 200, ContentLength: 10, Title: "A"}, "aa"),
+		{URL: "http://b.example.com", StatusCode: 302, Location: "https://a.example.com/"},
+		withBody(&Record{URL: "https://b.example.com", StatusCode: 200, ContentLength: 10, Title: "A"}, "aa"),
+	}
+	kept, services := DedupeServices(records)
+	if len(services) != 3 {
+		t.Fatalf("got %d services, want 3", len(services))
+	}
+	var urls []string
+	for _, r := range kept {
+		urls = append(urls, r.URL)
+	}
+	want := []string{"https://a.example.com", "http://b.example.com", "https://b.example.com"}
+	if len(urls) != len(want) {
+		t.Fatalf("kept %v, want %v", urls, want)
+	}
+	for i := range want {
+		if urls[i] != want[i] {
+			t.Errorf("kept %v, want %v", urls, want)
+			break
+		}
+	}
+	if len(services[0].Alternates) != 2 {
+		t.Errorf("alternates of %s = %v, want 2", services[0].Canonical, services[0].Alternates)
+	}
+}
+
+func TestDedupeServicesWithoutBodyHash(t *testing.T) {
+	records := []*Record{
+		{URL: "https://a.example.com", StatusCode: 200, ContentLength: 10, Title: "Login"},
+		{URL: "https://a.example.com:8443", StatusCode: 200, ContentLength: 10, Title: "Login"},
+		withBody(&Record{URL: "https://a.example.com:9443", StatusCode: 200, ContentLength: 10, Title: "Login"}, "aa"),
+		withBody(&Record{URL: "https://a.example.com:10443", StatusCode: 200, ContentLength: 10, Title: "Login"}, "bb"),
+		withBody(&Record{URL: "http://a.example.com:8080", StatusCode: 200, ContentLength: 10, Title: "Login"}, "bb"),
+	}
+
+	// Only the records with the same body hash are merged
+	kept, services := DedupeServices(records)
+	if len(kept) != 4 || len(services) != 4 {
+		t.Fatalf("kept %d records in %d services, want 4", len(kept), len(services))
+	}
+	s := services[3]
+	if s.Canonical != "https://a.example.com:10443" || len(s.Alternates) != 1 ||
+		s.Alternates[0].URL != "http://a.example.com:8080" {
+		t.Errorf("service = %+v, want https://a.example.com:10443 with alternate http://a.example.com:8080", s)
+	}
+}
+
+func withBody(r *Record, bodyHash string) *Record {
+	r.Hash.BodySHA256 = bodyHash
+	return r
+}
@@ -0,0 +1,269 @@
+package purify
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CountEntry is a value together with the amount of records having it.
+type CountEntry struct {
+	Value string
+	Count int
+}
+
+// TreeNode is a label of the domain tree. Hosts is the amount of hosts in the
+// subtree, Host is set if the node itself is a host.
+type TreeNode struct {
+	Name     string
+	Full     string
+	Hosts    int
+	Host     bool
+	Children []*TreeNode
+
+	byName map[string]*TreeNode
+}
+
+// Statistics describe the shape of the attack surface.
+type Statistics struct {
+	Created      string
+	Hosts        int
+	Records      int
+	Tree         []*TreeNode
+	StatusCodes  []CountEntry
+	Technologies []CountEntry
+	Titles       []CountEntry
+	Ports        []CountEntry
+	Webservers   []CountEntry
+}
+
+// ComputeStatistics builds the domain tree of the hosts of the records and
+// counts the top values of the status codes, technologies, titles, ports and
+// web servers.
+func ComputeStatistics(records []*Record, top int) *Statistics {
+	stats := &Statistics{Created: time.Now().Format(time.RFC3339), Records: len(records)}
+	statusCodes := make(map[string]int)
+	technologies := make(map[string]int)
+	titles := make(map[string]int)
+	ports := make(map[string]int)
+	webservers := make(map[string]int)
+
+	root := &TreeNode{byName: make(map[string]*TreeNode)}
+	seen := make(map[string]bool)
+	for _, r := range records {
+		if r.StatusCode > 0 {
+			statusCodes[strconv.Itoa(r.StatusCode)]++
+		}
+		for _, tech := range r.Tech {
+			technologies[tech]++
+		}
+		if r.Title != "" {
+			titles[r.Title]++
+		}
+		if r.Webserver != "" {
+			webservers[r.Webserver]++
+		}
+		if p := servicePort(r); p != "" {
+			ports[p]++
+		}
+
+		host := HostName(r)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		stats.Hosts++
+		root.insert(host)
+	}
+
+	stats.Tree = root.sorted()
+	stats.StatusCodes = topValues(statusCodes, top)
+	stats.Technologies = topValues(technologies, top)
+	stats.Titles = topValues(titles, top)
+	stats.Ports = topValues(ports, top)
+	stats.Webservers = topValues(webservers, top)
+	return stats
+}
+
+// insert adds the host below its registrable domain, one node per label of
+// the subdomain. IP addresses and invalid names are added as they are.
+func (n *TreeNode) insert(host string) {
+	parts := SplitHost(host)
+	path := []string{host}
+	if parts.Domain != "" {
+		path = []string{parts.Domain}
+		if parts.Subdomain != "" {
+			labels := strings.Split(parts.Subdomain, ".")
+			for i := len(labels) - 1; i >= 0; i-- {
+				path = append(path, labels[i])
+			}
+		}
+	}
+
+	node := n
+	full := ""
+	for _, name := range path {
+		if full == "" {
+			full = name
+		} else {
+			full = name + "." + full
+		}
+		child, ok := node.byName[name]
+		if !ok {
+			child = &TreeNode{Name: name, Full: full, byName: make(map[string]*TreeNode)}
+			node.byName[name] = child
+			node.Children = append(node.Children, child)
+		}
+		child.Hosts++
+		node = child
+	}
+	node.Host = true
+}
+
+// sorted sorts the children by the amount of hosts and returns them.
+func (n *TreeNode) sorted() []*TreeNode {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		if n.Children[i].Hosts != n.Children[j].Hosts {
+			return n.Children[i].Hosts > n.Children[j].Hosts
+		}
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, child := range n.Children {
+		child.sorted()
+	}
+	return n.Children
+}
+
+// topValues returns the top values ordered by their count.
+func topValues(counts map[string]int, top int) []CountEntry {
+	var entries []CountEntry
+	for value, count := range counts {
+		entries = append(entries, CountEntry{Value: value, Count: count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Value < entries[j].Value
+	})
+	if top > 0 && len(entries) > top {
+		entries = entries[:top]
+	}
+	return entries
+}
+
+// servicePort returns the port of the record, the default port of the scheme
+// if none is given.
+func servicePort(r *Record) string {
+	if p := recordPort(r); p != "" {
+		return p
+	}
+	switch recordScheme(r) {
+	case "https":
+		return "443"
+	case "http":
+		return "80"
+	}
+	return ""
+}
+
+// WriteText writes the domain tree and the statistics for the terminal.
+func (s *Statistics) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "%d hosts, %d records\n\n", s.Hosts, s.Records)
+	for _, node := range s.Tree {
+		fmt.Fprintf(w, "%s (%d)\n", node.Name, node.Hosts)
+		writeTreeChildren(w, node, "")
+	}
+
+	sections := []struct {
+		title   string
+		entries []CountEntry
+	}{
+		{"Status codes", s.StatusCodes},
+		{"Technologies", s.Technologies},
+		{"Titles", s.Titles},
+		{"Ports", s.Ports},
+		{"Web servers", s.Webservers},
+	}
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		for _, entry := range section.entries {
+			fmt.Fprintf(w, "  %6d  %s\n", entry.Count, entry.Value)
+		}
+	}
+}
+
+func writeTreeChildren(w io.Writer, node *TreeNode, indent string) {
+	for i, child := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s (%d)\n", indent, branch, child.Name, child.Hosts)
+		writeTreeChildren(w, child, indent+next)
+	}
+}
+
+// statisticsTable is a titled table of the statistics page.
+type statisticsTable struct {
+	Title   string
+	Entries []CountEntry
+}
+
+var statisticsTemplate = template.Must(template.New("statistics").Funcs(template.FuncMap{
+	"entries": func(title string, entries []CountEntry) statisticsTable {
+		return statisticsTable{Title: title, Entries: entries}
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Attack surface</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+ul { list-style: none; padding-left: 1.2em; border-left: 1px solid #ccc; }
+summary, li > span { cursor: default; line-height: 1.6; }
+.count { color: #888; }
+.host { font-weight: bold; }
+.tables { display: flex; flex-wrap: wrap; gap: 2em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.2em 0.8em; border-bottom: 1px solid #eee; }
+td.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Attack surface</h1>
+<p>{{.Hosts}} hosts, {{.Records}} records, created {{.Created}}</p>
+<h2>Domains</h2>
+{{template "tree" .Tree}}
+<h2>Statistics</h2>
+<div class="tables">
+{{template "table" (entries "Status code" .StatusCodes)}}
+{{template "table" (entries "Technology" .Technologies)}}
+{{template "table" (entries "Title" .Titles)}}
+{{template "table" (entries "Port" .Ports)}}
+{{template "table" (entries "Web server" .Webservers)}}
+</div>
+</body>
+</html>
+{{define "tree"}}<ul>
+{{range .}}<li>{{if .Children}}<details{{if lt .Hosts 50}} open{{end}}><summary><span{{if .Host}} class="host"{{end}} title="{{.Full}}">{{.Name}}</span> <span class="count">({{.Hosts}})</span></summary>{{template "tree" .Children}}</details>{{else}}<span{{if .Host}} class="host"{{end}} title="{{.Full}}">{{.Name}}</span>{{end}}</li>
+{{end}}</ul>{{end}}
+{{define "table"}}{{if .Entries}}<table>
+<tr><th>{{.Title}}</th><th>Records</th></tr>
+{{range .Entries}}<tr><td>{{.Value}}</td><td class="number">{{.Count}}</td></tr>
+{{end}}</table>{{end}}{{end}}
+`))
+
+// WriteHTML writes the domain tree and the statistics as self-contained HTML
+// page.
+func (s *Statistics) WriteHTML(w io.Writer) error {
+	return statisticsTemplate.Execute(w, s)
+}
@@ -0,0 +1,103 @@
+package purify
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeStatistics(t *testing.T) {
+	records := []*Record{
+		{URL: "https://www.example.com", StatusCode: 200, Title: "Example", Webserver: "nginx", Tech: []string{"Nginx", "PHP"}},
+		{URL: "http://www.example.com", StatusCode: 301, Webserver: "nginx"},
+		{URL: "https://api.dev.example.com:8443", StatusCode: 200, Title: "Example", Tech: []string{"Nginx"}},
+		{URL: "https://shop.example.org", StatusCode: 403, Title: "Forbidden"},
+		{URL: "https://192.0.2.1", StatusCode: 200},
+	}
+	stats := ComputeStatistics(records, 2)
+
+	if stats.Hosts != 4 || stats.Records != 5 {
+		t.Errorf("got %d hosts and %d records, want 4 and 5", stats.Hosts, stats.Records)
+	}
+	if want := []CountEntry{{"200", 3}, {"301", 1}}; !reflect.DeepEqual(stats.StatusCodes, want) {
+		t.Errorf("status codes = %v, want %v", stats.StatusCodes, want)
+	}
+	if want := []CountEntry{{"Nginx", 2}, {"PHP", 1}}; !reflect.DeepEqual(stats.Technologies, want) {
+		t.Errorf("technologies = %v, want %v", stats.Technologies, want)
+	}
+	if want := []CountEntry{{"Example", 2}, {"Forbidden", 1}}; !reflect.DeepEqual(stats.Titles, want) {
+		t.Errorf("titles = %v, want %v", stats.Titles, want)
+	}
+	if want := []CountEntry{{"443", 3}, {"80", 1}}; !reflect.DeepEqual(stats.Ports, want) {
+		t.Errorf("ports = %v, want %v", stats.Ports, want)
+	}
+	if want := []CountEntry{{"nginx", 2}}; !reflect.DeepEqual(stats.Webservers, want) {
+		t.Errorf("web servers = %v, want %v", stats.Webservers, want)
+	}
+
+	var tree []string
+	var walk func(nodes []*TreeNode)
+	walk = func(nodes []*TreeNode) {
+		for _, node := range nodes {
+			tree = append(tree, node.Full)
+			walk(node.Children)
+		}
+	}
+	walk(stats.Tree)
+	want := []string{"example.com", "dev.example.com", "api.dev.example.com", "www.example.com", "192.0.2.1",
+		"example.org", "shop.example.org"}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("tree = %q, want %q", tree, want)
+	}
+	if stats.Tree[0].Hosts != 2 || stats.Tree[0].Host || !stats.Tree[0].Children[1].Host {
+		t.Errorf("example.com node = %+v, want 2 hosts and www as host", stats.Tree[0])
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	records := []*Record{
+		{URL: "https://www.example.com", StatusCode: 200},
+		{URL: "https://api.example.com", StatusCode: 200},
+	}
+	var buf bytes.Buffer
+	ComputeStatistics(records, 10).WriteText(&buf)
+	want := `2 hosts, 2 records
+
+example.com (2)
+├── api (1)
+└── www (1)
+
+Status codes:
+       2  200
+
+Ports:
+       2  443
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteHTMLEscaping(t *testing.T) {
+	records := []*Record{
+		{URL: "https://www.example.com", StatusCode: 200, Title: `<script>alert("title")</script>`},
+		{Input: `<img src=x onerror=alert(1)>`, StatusCode: 200, Webserver: `"><b>nginx`},
+	}
+	var buf bytes.Buffer
+	if err := ComputeStatistics(records, 10).WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, raw := range []string{"<script>alert", "<img src=x", `"><b>nginx`} {
+		if strings.Contains(page, raw) {
+			t.Errorf("page contains unescaped %q", raw)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;alert(&#34;title&#34;)&lt;/script&gt;", "&lt;img src=x onerror=alert(1)&gt;",
+		"&#34;&gt;&lt;b&gt;nginx"} {
+		if !strings.Contains(page, escaped) {
+			t.Errorf("page doesn't contain escaped %q", escaped)
+		}
+	}
+}
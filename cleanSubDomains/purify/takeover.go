@@ -0,0 +1,256 @@
+package purify
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed fingerprints.json
+var builtinFingerprints string
+
+// TakeoverCandidate is a host whose CNAME points at a third-party service and
+// which looks unclaimed, together with the evidence for it.
+type TakeoverCandidate struct {
+	Host     string   `json:"host"`
+	URL      string   `json:"url,omitempty"`
+	CNAME    string   `json:"cname"`
+	Service  string   `json:"service"`
+	Status   string   `json:"status,omitempty"`
+	Evidence []string `json:"evidence"`
+}
+
+// Fingerprint is the response of a third-party service for a name pointing at
+// it which is not claimed by anyone. The fields are the ones of the
+// fingerprints of can-i-take-over-xyz, extended by the title.
+type Fingerprint struct {
+	Service    string   `json:"service"`
+	CNAME      []string `json:"cname"`
+	Body       string   `json:"fingerprint"`
+	Title      string   `json:"title,omitempty"`
+	HTTPStatus int      `json:"http_status"`
+	NXDomain   bool     `json:"nxdomain"`
+	Status     string   `json:"status"`
+	Vulnerable bool     `json:"vulnerable"`
+}
+
+// claimable checks if names of the service can be claimed, at least in some
+// cases.
+func (fp *Fingerprint) claimable() bool {
+	return fp.Vulnerable || strings.EqualFold(fp.Status, "edge case")
+}
+
+// matchCNAME returns the first of the CNAMEs which is below a CNAME suffix of
+// the service.
+func (fp *Fingerprint) matchCNAME(cnames []string) (string, string) {
+	for _, cname := range cnames {
+		cname = strings.TrimSuffix(strings.ToLower(cname), ".")
+		for _, suffix := range fp.CNAME {
+			suffix = strings.Trim(strings.ToLower(suffix), ".")
+			if suffix != "" && (cname == suffix || strings.HasSuffix(cname, "."+suffix)) {
+				return cname, suffix
+			}
+		}
+	}
+	return "", ""
+}
+
+// FingerprintTable contains the takeover fingerprints of third-party
+// services.
+type FingerprintTable struct {
+	fingerprints []*Fingerprint
+	services     map[string]bool
+}
+
+// NewFingerprintTable returns an empty fingerprint table.
+func NewFingerprintTable() *FingerprintTable {
+	return &FingerprintTable{services: make(map[string]bool)}
+}
+
+// LoadFile adds the fingerprints of the file.
+func (t *FingerprintTable) LoadFile(tableFile string) error {
+	file, err := os.Open(tableFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return t.Load(file, tableFile)
+}
+
+// LoadBuiltin adds the built-in fingerprints.
+func (t *FingerprintTable) LoadBuiltin() error {
+	return t.Load(strings.NewReader(builtinFingerprints), "fingerprints.json")
+}
+
+// Load adds the fingerprints of a JSON array, e.g. the fingerprints.json of
+// can-i-take-over-xyz. Services which are already known are not overwritten,
+// so a service can be disabled by marking it as not vulnerable.
+func (t *FingerprintTable) Load(reader io.Reader, source string) error {
+	var fingerprints []*Fingerprint
+	if err := json.NewDecoder(reader).Decode(&fingerprints); err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	added := make(map[string]bool)
+	for i, fp := range fingerprints {
+		if fp == nil || strings.TrimSpace(fp.Service) == "" {
+			return fmt.Errorf("%s: fingerprint %d has no service", source, i+1)
+		}
+		name := strings.ToLower(strings.TrimSpace(fp.Service))
+		if t.services[name] && !added[name] {
+			continue
+		}
+		added[name] = true
+		t.fingerprints = append(t.fingerprints, fp)
+	}
+	for name := range added {
+		t.services[name] = true
+	}
+	return nil
+}
+
+// CheckRecords matches the responses of the records against the fingerprints
+// of the services their CNAMEs point at. The CNAMEs of httpx (-cname) are
+// completed by the ones returned by cnames, which may be nil. The body is
+// taken from the httpx output (-irr, -bp) or the stored response (-sr), if
+// it isn't available the title has to match. At most one candidate is
+// returned per host.
+func (t *FingerprintTable) CheckRecords(records []*Record, cnames func(host string) []string) []*TakeoverCandidate {
+	candidates := []*TakeoverCandidate{}
+	seen := make(map[string]bool)
+	for _, r := range records {
+		host := HostName(r)
+		if seen[host] {
+			continue
+		}
+		names := r.CNAME
+		if cnames != nil {
+			names = append(append([]string(nil), names...), cnames(host)...)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		body, complete := recordBody(r)
+		for _, fp := range t.fingerprints {
+			if !fp.claimable() || fp.NXDomain {
+				continue
+			}
+			cname, suffix := fp.matchCNAME(names)
+			if cname == "" {
+				continue
+			}
+			bodyMatches := fp.Body != "" && strings.Contains(body, fp.Body)
+			titleMatches := fp.Title != "" && strings.Contains(strings.ToLower(r.Title), strings.ToLower(fp.Title))
+			if !bodyMatches && (!titleMatches || fp.Body != "" && complete) {
+				continue
+			}
+			evidence := []string{fmt.Sprintf("CNAME %s matches %s", cname, suffix)}
+			if bodyMatches {
+				evidence = append(evidence, fmt.Sprintf("body contains %q", fp.Body))
+			} else if fp.Body != "" {
+				evidence = append(evidence, "complete body not available")
+			}
+			if titleMatches {
+				evidence = append(evidence, fmt.Sprintf("title contains %q", fp.Title))
+			}
+			if fp.HTTPStatus != 0 && r.StatusCode == fp.HTTPStatus {
+				evidence = append(evidence, fmt.Sprintf("status code %d", r.StatusCode))
+			}
+			seen[host] = true
+			candidates = append(candidates, &TakeoverCandidate{
+				Host:     host,
+				URL:      r.URL,
+				CNAME:    cname,
+				Service:  fp.Service,
+				Status:   fp.Status,
+				Evidence: evidence,
+			})
+			log.Warnf("Possible subdomain takeover of %s, CNAME %s at %s: %s", host, cname, fp.Service,
+				strings.Join(evidence, ", "))
+			break
+		}
+	}
+	return candidates
+}
+
+// CheckHosts matches the imported hosts whose name doesn't exist against the
+// fingerprints of services which are claimable if the CNAME target doesn't
+// exist (nxdomain).
+func (t *FingerprintTable) CheckHosts(inv *Inventory) []*TakeoverCandidate {
+	candidates := []*TakeoverCandidate{}
+	for _, entry := range inv.hosts {
+		if entry.DNSStatus != "NXDOMAIN" || len(entry.CNAMEs) == 0 {
+			continue
+		}
+		for _, fp := range t.fingerprints {
+			if !fp.claimable() || !fp.NXDomain {
+				continue
+			}
+			cname, suffix := fp.matchCNAME(entry.CNAMEs)
+			if cname == "" {
+				continue
+			}
+			evidence := []string{fmt.Sprintf("CNAME %s matches %s", cname, suffix), "DNS status NXDOMAIN"}
+			candidates = append(candidates, &TakeoverCandidate{
+				Host:     entry.Host,
+				CNAME:    cname,
+				Service:  fp.Service,
+				Status:   fp.Status,
+				Evidence: evidence,
+			})
+			log.Warnf("Possible subdomain takeover of %s, CNAME %s at %s: %s", entry.Host, cname, fp.Service,
+				strings.Join(evidence, ", "))
+			break
+		}
+	}
+	return candidates
+}
+
+// recordBody returns the response body of the record and if it is complete.
+// The body preview of httpx only contains the start of the body.
+func recordBody(r *Record) (string, bool) {
+	if r.Body != "" {
+		return r.Body, true
+	}
+	if r.StoredResponsePath != "" {
+		content, err := os.ReadFile(r.StoredResponsePath)
+		if err == nil {
+			return string(responseBody(content)), true
+		}
+		log.Warnf("Could not read stored response of %s: %v", r.URL, err)
+	}
+	return r.BodyPreview, false
+}
+
+// MergeTakeovers merges the candidates of the same host, in the order they
+// have been found. The evidence of all candidates of a host is combined.
+func MergeTakeovers(lists ...[]*TakeoverCandidate) []*TakeoverCandidate {
+	merged := []*TakeoverCandidate{}
+	byHost := make(map[string]*TakeoverCandidate)
+	for _, candidates := range lists {
+		for _, c := range candidates {
+			existing, ok := byHost[c.Host]
+			if !ok {
+				copied := *c
+				copied.Evidence = append([]string(nil), c.Evidence...)
+				byHost[c.Host] = &copied
+				merged = append(merged, &copied)
+				continue
+			}
+			if existing.URL == "" {
+				existing.URL = c.URL
+			}
+			if existing.Status == "" {
+				existing.Status = c.Status
+			}
+			for _, evidence := range c.Evidence {
+				existing.Evidence = appendUnique(existing.Evidence, evidence)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,124 @@
+package purify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func builtinFingerprintTable(t *testing.T, user string) *FingerprintTable {
+	t.Helper()
+
+	table := NewFingerprintTable()
+	if user != "" {
+		if err := table.Load(strings.NewReader(user), "user.json"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.LoadBuiltin(); err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func TestCheckRecords(t *testing.T) {
+	stored := filepath.Join(t.TempDir(), "response.txt")
+	response := "HTTP/1.1 404 Not Found\r\nContent-Type: text/html\r\n\r\n<p>Sorry, this shop is currently unavailable.</p>"
+	if err := os.WriteFile(stored, []byte(response), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []*Record{
+		{URL: "https://docs.example.com", Input: "docs.example.com", StatusCode: 404, CNAME: []string{"example.github.io"},
+			Body: "<p>There isn't a GitHub Pages site here.</p>"},
+		{URL: "http://docs.example.com", Input: "docs.example.com", StatusCode: 404, CNAME: []string{"example.github.io"},
+			Body: "<p>There isn't a GitHub Pages site here.</p>"},
+		{URL: "https://shop.example.com", Input: "shop.example.com", StatusCode: 404, CNAME: []string{"example.myshopify.com"},
+			StoredResponsePath: stored},
+		{URL: "https://app.example.com", Input: "app.example.com", StatusCode: 404, Title: "Heroku | No such app"},
+		{URL: "https://pages.example.com", Input: "pages.example.com", StatusCode: 404, CNAME: []string{"pages.github.io"},
+			Body: "<h1>Welcome</h1>"},
+		{URL: "https://www.example.com", Input: "www.example.com", StatusCode: 404, CNAME: []string{"www.example-cdn.com"},
+			Body: "Repository not found"},
+		{URL: "https://repo.example.com", Input: "repo.example.com", StatusCode: 200, CNAME: []string{"repo.bitbucket.io"},
+			BodyPreview: "<html><head>"},
+	}
+	cnames := func(host string) []string {
+		if host == "app.example.com" {
+			return []string{"example-app.herokuapp.com."}
+		}
+		return nil
+	}
+	candidates := builtinFingerprintTable(t, "").CheckRecords(records, cnames)
+
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.URL+" "+c.Service+": "+strings.Join(c.Evidence, ", "))
+	}
+	want := []string{
+		`https://docs.example.com GitHub: CNAME example.github.io matches github.io, body contains "There isn't a GitHub Pages site here.", status code 404`,
+		`https://shop.example.com Shopify: CNAME example.myshopify.com matches myshopify.com, body contains "Sorry, this shop is currently unavailable.", status code 404`,
+		`https://app.example.com Heroku: CNAME example-app.herokuapp.com matches herokuapp.com, complete body not available, title contains "No such app", status code 404`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates = %q, want %q", got, want)
+	}
+}
+
+func TestFingerprintPrecedence(t *testing.T) {
+	user := `[
+  {"cicd_pass": true, "cname": ["github.io"], "discussion": "", "documentation": "", "fingerprint": "There isn't a GitHub Pages site here.", "http_status": null, "nxdomain": false, "service": "GitHub", "status": "Not vulnerable", "vulnerable": false},
+  {"cname": ["pages.corp.example"], "fingerprint": "No such page site", "http_status": 404, "nxdomain": false, "service": "Corp Pages", "status": "Vulnerable", "vulnerable": true}
+]`
+	records := []*Record{
+		{URL: "https://docs.example.com", Input: "docs.example.com", CNAME: []string{"example.github.io"},
+			Body: "<p>There isn't a GitHub Pages site here.</p>"},
+		{URL: "https://intranet.example.com", Input: "intranet.example.com", CNAME: []string{"intranet.pages.corp.example"},
+			Body: "No such page site"},
+	}
+	candidates := builtinFingerprintTable(t, user).CheckRecords(records, nil)
+	if len(candidates) != 1 || candidates[0].Service != "Corp Pages" {
+		t.Errorf("candidates = %+v, want only Corp Pages", candidates)
+	}
+}
+
+func TestLoadFingerprintsInvalid(t *testing.T) {
+	for _, content := range []string{`{"service": "GitHub"}`, `[{"cname": ["github.io"]}]`} {
+		if err := NewFingerprintTable().Load(strings.NewReader(content), "user.json"); err == nil {
+			t.Errorf("Load(%s) succeeded, want error", content)
+		}
+	}
+}
+
+func TestCheckHosts(t *testing.T) {
+	inv := importResolverOutputs(t)
+	inv.resolve(resolution{name: "web.example.com", status: "NXDOMAIN",
+		cnames: []string{"example-web.azurewebsites.net"}}, formatDnsx)
+	table := builtinFingerprintTable(t, "")
+
+	candidates := table.CheckHosts(inv)
+	if len(candidates) != 1 || candidates[0].Host != "web.example.com" || candidates[0].Service != "Microsoft Azure" {
+		t.Errorf("candidates = %+v, want web.example.com at Microsoft Azure", candidates)
+	}
+
+	providers := NewProviderTable()
+	if err := providers.LoadBuiltin(); err != nil {
+		t.Fatal(err)
+	}
+	merged := MergeTakeovers(candidates, inv.DanglingCNAMEs(providers))
+	var hosts []string
+	for _, c := range merged {
+		hosts = append(hosts, c.Host)
+	}
+	want := []string{"web.example.com", "blog.example.com", "assets.example.com", "static.example.com"}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("merged hosts = %q, want %q", hosts, want)
+	}
+	wantEvidence := []string{"CNAME example-web.azurewebsites.net matches azurewebsites.net", "DNS status NXDOMAIN",
+		"CNAME chain doesn't resolve to an address", "no HTTP response"}
+	if !reflect.DeepEqual(merged[0].Evidence, wantEvidence) {
+		t.Errorf("merged evidence = %q, want %q", merged[0].Evidence, wantEvidence)
+	}
+}
@@ -0,0 +1,126 @@
+package purify
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DroppedRecord is a record which has been removed from the purified output
+// together with the explanation why.
+type DroppedRecord struct {
+	URL    string `json:"url"`
+	Host   string `json:"host"`
+	Reason string `json:"reason"`
+}
+
+// WildcardZone is a parent domain which answers for many of its children with
+// the same addresses and the same response, caused by wildcard DNS records and
+// catch-all virtual hosts.
+type WildcardZone struct {
+	Zone           string          `json:"zone"`
+	Addresses      []string        `json:"addresses"`
+	Response       string          `json:"response"`
+	Representative string          `json:"representative"`
+	Dropped        []DroppedRecord `json:"dropped"`
+}
+
+// DetectWildcards marks a parent domain as wildcard zone if at least
+// minChildren of its direct children resolve to the same addresses (httpx a
+// and cname) and return the same response. Only the records of the first host
+// of each wildcard zone are kept, all others are returned as dropped.
+func DetectWildcards(records []*Record, minChildren int) ([]*Record, []*WildcardZone) {
+	type bucket struct {
+		zone      string
+		addresses []string
+		response  string
+		records   []*Record
+		hosts     []string
+	}
+	var buckets []*bucket
+	byKey := make(map[string]*bucket)
+
+	for _, r := range records {
+		host := HostName(r)
+		if SplitHost(host).Subdomain == "" {
+			// Registrable domains have no parent zone which could be a wildcard
+			continue
+		}
+		addresses := recordAddresses(r)
+		if len(addresses) == 0 {
+			continue
+		}
+
+		zone := host[strings.Index(host, ".")+1:]
+		response := responseSignature(r)
+		key := zone + "|" + strings.Join(addresses, ",") + "|" + response
+		b, ok := byKey[key]
+		if !ok {
+			b = &bucket{zone: zone, addresses: addresses, response: response}
+			byKey[key] = b
+			buckets = append(buckets, b)
+		}
+		b.records = append(b.records, r)
+		b.hosts = appendUnique(b.hosts, host)
+	}
+
+	dropped := make(map[*Record]bool)
+	var zones []*WildcardZone
+	for _, b := range buckets {
+		if len(b.hosts) < minChildren {
+			continue
+		}
+		z := &WildcardZone{
+			Zone:           "*." + b.zone,
+			Addresses:      b.addresses,
+			Response:       b.response,
+			Representative: b.records[0].URL,
+		}
+		reason := fmt.Sprintf("wildcard %s: %d hosts resolve to %s and return the same response (%s), kept %s",
+			z.Zone, len(b.hosts), strings.Join(b.addresses, ", "), b.response, z.Representative)
+		for _, r := range b.records {
+			if HostName(r) == b.hosts[0] {
+				continue
+			}
+			dropped[r] = true
+			z.Dropped = append(z.Dropped, DroppedRecord{URL: r.URL, Host: HostName(r), Reason: reason})
+		}
+		log.Infof("Detected wildcard zone %s, dropped %d records of %d hosts", z.Zone, len(z.Dropped), len(b.hosts)-1)
+		zones = append(zones, z)
+	}
+
+	var remaining []*Record
+	for _, r := range records {
+		if !dropped[r] {
+			remaining = append(remaining, r)
+		}
+	}
+	return remaining, zones
+}
+
+// recordAddresses returns the sorted addresses and CNAMEs a record resolved
+// to. If httpx didn't output them the IP address of the host is used.
+func recordAddresses(r *Record) []string {
+	var addresses []string
+	addresses = append(addresses, r.A...)
+	for _, cname := range r.CNAME {
+		addresses = append(addresses, strings.TrimSuffix(strings.ToLower(cname), "."))
+	}
+	if len(addresses) == 0 && net.ParseIP(r.Host) != nil {
+		addresses = append(addresses, r.Host)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// responseSignature describes a response in a way which doesn't change if the
+// requested host name is reflected in the title or body, so the host name is
+// removed from the title and the content length isn't used but the number of
+// words and lines.
+func responseSignature(r *Record) string {
+	return fmt.Sprintf("status=%d title=%q words=%d lines=%d", r.StatusCode, withoutHost(r.Title, HostName(r)),
+		r.Words, r.Lines)
+}
@@ -0,0 +1,51 @@
+package purify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectWildcardsCountsHosts(t *testing.T) {
+	record := func(url string, host string) *Record {
+		return &Record{URL: url, Input: host, StatusCode: 200, Title: "Default page", Words: 120, Lines: 30,
+			A: []string{"203.0.113.10"}}
+	}
+	records := []*Record{
+		record("http://a.example.com", "a.example.com"),
+		record("https://a.example.com", "a.example.com"),
+		record("http://b.example.com", "b.example.com"),
+	}
+	remaining, zones := DetectWildcards(records, 3)
+	if len(zones) != 0 || len(remaining) != 3 {
+		t.Errorf("2 hosts detected as wildcard zone %v", zones)
+	}
+
+	records = append(records, record("http://c.example.com", "c.example.com"))
+	remaining, zones = DetectWildcards(records, 3)
+	if len(zones) != 1 {
+		t.Fatalf("got %d zones, want 1", len(zones))
+	}
+	var urls []string
+	for _, r := range remaining {
+		urls = append(urls, r.URL)
+	}
+	if want := []string{"http://a.example.com", "https://a.example.com"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("kept %q, want %q", urls, want)
+	}
+	if len(zones[0].Dropped) != 2 {
+		t.Errorf("dropped %d records, want 2", len(zones[0].Dropped))
+	}
+}
+
+func TestDetectWildcardsReflectedTitle(t *testing.T) {
+	var records []*Record
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		records = append(records, &Record{URL: "https://" + host, Input: host, StatusCode: 200,
+			Title: "Welcome to " + strings.ToUpper(host), Words: 120, Lines: 30, A: []string{"203.0.113.10"}})
+	}
+	remaining, zones := DetectWildcards(records, 3)
+	if len(zones) != 1 || len(remaining) != 1 {
+		t.Errorf("got %d zones and %d remaining records, want 1 zone and 1 record", len(zones), len(remaining))
+	}
+}
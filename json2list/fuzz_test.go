@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzCreateWordList feeds arbitrary data to the JSON traversal and the
+// morphology stage. Invalid JSON must be reported as error, never as panic.
+func FuzzCreateWordList(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.json"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`"abgelehnt"`))
+	f.Add([]byte(`[["zeit", ["buchung"]], {"zeitbuchungen": null}]`))
+	f.Add([]byte(`{"a": {"b": [1, 2.5e3, true, {"c-1": "1-2"}]}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, _, err := createWordList(data, nil, orderSource)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if !checkForInclusion(entry) {
+				t.Errorf("entry %q should have been excluded", entry)
+			}
+		}
+
+		morph := newMorphology("de,en", "", "")
+		if _, _, err := createWordList(data, morph, orderSource); err != nil {
+			t.Errorf("createWordList with morphology failed: %v", err)
+		}
+	})
+}
+
+func FuzzCheckForInclusion(f *testing.F) {
+	for _, seed := range []string{"", "id", "1-2-3", "ext-5", "Zeitänderung", "#EF5350", "a b"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		if checkForInclusion(content) {
+			var entries []string
+			add(content, &entries, make(map[string]int))
+		}
+	})
+}
@@ -2,13 +2,14 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func main() {
@@ -30,77 +31,205 @@ func main() {
 	flag.BoolVar(&useOnlyLowerCase, "lower", false, "")
 	flag.BoolVar(&useOnlyLowerCase, "l", false,"")
 
+	var useMorphology bool
+	flag.BoolVar(&useMorphology, "morph", false, "")
+
+	var languages string
+	flag.StringVar(&languages, "lang", "de,en", "")
+
+	var rules string
+	flag.StringVar(&rules, "rules", "", "")
+
+	var dictFile string
+	flag.StringVar(&dictFile, "dict", "", "")
+
+	var tagged bool
+	flag.BoolVar(&tagged, "tagged", false, "")
+
+	var order string
+	flag.StringVar(&order, "sort", orderSource, "")
+
+	var candidateFile string
+	flag.StringVar(&candidateFile, "candidates", "", "")
+
+	var profileFile string
+	flag.StringVar(&profileFile, "profile", "", "")
+
+	var neighbours int
+	flag.IntVar(&neighbours, "neighbours", 5, "")
+
+	var yearSpan int
+	flag.IntVar(&yearSpan, "years", 2, "")
+
+	var watchDirs string
+	flag.StringVar(&watchDirs, "watch", "", "")
+
+	var pattern string
+	flag.StringVar(&pattern, "pattern", "*.json", "")
+
+	var interval time.Duration
+	flag.DurationVar(&interval, "interval", 2*time.Second, "")
+
+	var storeFile string
+	flag.StringVar(&storeFile, "store", "json2list.store.json", "")
+
+	var serveAddress string
+	flag.StringVar(&serveAddress, "serve", "", "")
+
 	flag.Parse()
 
 	//fmt.Println("All options parsed")
 
-	const maxCapacity = 512 * 1024
-	buf := make([]byte, maxCapacity)
+	if err := checkLanguages(languages); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := checkPatternFiles(outputFile, candidateFile, profileFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
 
+	var morph *morphology
+	if useMorphology {
+		morph = newMorphology(languages, rules, dictFile)
+	}
+
+	if watchDirs != "" {
+		w, err := newWatcher(watchDirs, pattern, interval, storeFile, outputFile, morph, order, tagged)
+		if err == nil {
+			err = w.run(serveAddress)
+		}
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var buf []byte
 	if isFlagPassed("i") || isFlagPassed("input") {
 		buf = readJsonFileToByte(inputFile)
 	} else {
-		// fetch for all domains from stdin
-		sc := bufio.NewScanner(os.Stdin)
+		// fetch the JSON data from stdin
+		buf, _ = ioutil.ReadAll(os.Stdin)
+	}
 
-		sc.Buffer(buf, maxCapacity)
+	if err := parseJsonToWordList(buf, outputFile, morph, order, tagged); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 
-	parseJsonToWordList(buf, outputFile)
+	if candidateFile != "" || profileFile != "" {
+		if candidateFile == "" {
+			candidateFile = os.DevNull
+		}
+		if err := writePatterns(buf, candidateFile, profileFile, neighbours, yearSpan, tagged); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 }
 
-func parseJsonToWordList(buffer []byte, outputFile string) {
+func parseJsonToWordList(buffer []byte, outputFile string, morph *morphology, order string, tagged bool) error {
+	entries, tags, err := createWordList(buffer, morph, order)
+	if err != nil {
+		return err
+	}
+
 	file, err := os.Create(outputFile)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 	defer file.Close()
 
-	var result map[string]interface{}
-	e := json.Unmarshal(buffer, &result)
+	return writeWordList(file, entries, tags, tagged)
+}
 
-	// panic on error
-	if e != nil {
-		panic(e)
+func writeWordList(writer io.Writer, entries []string, tags map[string]string, tagged bool) error {
+	w := bufio.NewWriter(writer)
+	for _, line := range entries {
+		if tagged {
+			rule, ok := tags[line]
+			if !ok {
+				rule = "json"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", line, rule)
+		} else {
+			fmt.Fprintln(w, line)
+		}
 	}
+	return w.Flush()
+}
 
+// createWordList parses the JSON data and returns the unique entries for the
+// wordlist in the requested order. Variants created by the morphology stage
+// are tagged with the rule which created them, original entries have no tag.
+func createWordList(buffer []byte, morph *morphology, order string) ([]string, map[string]string, error) {
 	var entries []string
-	var uniqueMap = make(map[string]bool)
+	var counts = make(map[string]int)
+	var tags = make(map[string]string)
 
-	parseMap(result, &entries, uniqueMap)
+	if err := harvest(buffer, &entries, counts, tags, morph); err != nil {
+		return nil, nil, err
+	}
 
-	w := bufio.NewWriter(file)
-	for _, line := range entries {
-		fmt.Fprintln(w, line)
+	if err := sortEntries(entries, counts, order); err != nil {
+		return nil, nil, err
 	}
-	w.Flush()
+	return entries, tags, nil
 }
 
-func parseMap(aMap map[string]interface{}, entries *[]string, uniqueMap map[string]bool) {
-	for key, val := range aMap {
+// harvest adds the entries found in the JSON data to the already existing
+// entries. Only the newly added entries are run through the morphology stage.
+func harvest(buffer []byte, entries *[]string, counts map[string]int, tags map[string]string, morph *morphology) error {
+	result, err := decodeOrdered(buffer)
+	if err != nil {
+		return err
+	}
+
+	start := len(*entries)
+	switch concreteVal := result.(type) {
+	case *object:
+		parseMap(concreteVal, entries, counts)
+	case []interface{}:
+		parseArray(concreteVal, entries, counts)
+	}
+
+	if morph != nil {
+		for _, v := range morph.variants((*entries)[start:]) {
+			if add(v.word, entries, counts) {
+				tags[(*entries)[len(*entries)-1]] = v.rule
+			}
+		}
+	}
+	return nil
+}
+
+func parseMap(aMap *object, entries *[]string, counts map[string]int) {
+	for _, key := range aMap.keys {
+		val := aMap.values[key]
 		switch concreteVal := val.(type) {
-		case map[string]interface{}:
+		case *object:
 			if checkForInclusion(key) {
-				add(key, entries, uniqueMap)
+				add(key, entries, counts)
 			}
-			parseMap(val.(map[string]interface{}), entries, uniqueMap)
+			parseMap(concreteVal, entries, counts)
 
 		case []interface{}:
 			if checkForInclusion(key) {
-				add(key, entries, uniqueMap)
+				add(key, entries, counts)
 			}
-			parseArray(val.([]interface{}), entries, uniqueMap)
+			parseArray(concreteVal, entries, counts)
 
 		case nil:
 			continue
 
 		case string:
 			if checkForInclusion(key) {
-				add(key, entries, uniqueMap)
+				add(key, entries, counts)
 			}
 
 			if checkForInclusion(concreteVal) {
-				add(concreteVal, entries, uniqueMap)
+				add(concreteVal, entries, counts)
 			}
 
 		default:
@@ -109,14 +238,14 @@ func parseMap(aMap map[string]interface{}, entries *[]string, uniqueMap map[stri
 	}
 }
 
-func parseArray(anArray []interface{}, entries *[]string, uniqueMap map[string]bool) {
+func parseArray(anArray []interface{}, entries *[]string, counts map[string]int) {
 	for _, val := range anArray {
 		switch concreteVal := val.(type) {
-		case map[string]interface{}:
-			parseMap(val.(map[string]interface{}), entries, uniqueMap)
+		case *object:
+			parseMap(concreteVal, entries, counts)
 
 		case []interface{}:
-			parseArray(val.([]interface{}), entries, uniqueMap)
+			parseArray(concreteVal, entries, counts)
 
 		case nil:
 			continue
@@ -124,7 +253,7 @@ func parseArray(anArray []interface{}, entries *[]string, uniqueMap map[string]b
 		case string:
 			if checkForInclusion(concreteVal) {
 				//fmt.Println("Adding value from array", concreteVal)
-				add(concreteVal, entries, uniqueMap)
+				add(concreteVal, entries, counts)
 			}
 
 		default:
@@ -133,15 +262,16 @@ func parseArray(anArray []interface{}, entries *[]string, uniqueMap map[string]b
 	}
 }
 
-func add(entry string, entries *[]string, uniqueMap map[string]bool) {
+func add(entry string, entries *[]string, counts map[string]int) bool {
 	if strings.ToUpper(entry) != entry {
 		entry = strings.ToLower(entry)
 	}
-	if uniqueMap[entry] {
-		return // Already in the map
+	counts[entry]++
+	if counts[entry] > 1 {
+		return false // Already in the map
 	}
 	*entries = append(*entries, entry)
-	uniqueMap[entry] = true
+	return true
 }
 
 func checkForInclusion(content string) bool {
@@ -238,6 +368,25 @@ func init() {
 			"  -k, --keys                Use only keys for the wordlist",
 			"  -v, --values              Use only the values for the wordlist",
 			"  -o, --output <file        File to store the created wordlist (will be created)",
+			"  -morph                    Add stems and inflection variants of the entries",
+			"  -lang <de,en>             Languages used for the morphology stage",
+			"  -rules <rule,...>         Only keep variants of the given rules (e.g. de-stem,en-plural)",
+			"  -dict <file>              Additional words used for splitting German compounds",
+			"  -tagged                   Write the producing rule after each entry (tab separated)",
+			"  -sort <mode>              Order of the entries: source (default), alpha, freq or length",
+			"",
+			"Pattern mode:",
+			"  -candidates <file>        Write candidate values generated from numbers and dates to the file",
+			"  -profile <file>           Write the profile of the numeric and date-like values per key as JSON",
+			"  -neighbours <n>           Number of neighbouring IDs and days generated per value (default 5)",
+			"  -years <n>                Number of years generated around the observed ones (default 2)",
+			"",
+			"Watch mode:",
+			"  -watch <dir,...>          Watch the directories and harvest new and changed files",
+			"  -pattern <glob>           Only harvest files matching the pattern (default *.json)",
+			"  -interval <duration>      Interval for polling the directories (default 2s)",
+			"  -store <file>             File to persist the harvested entries (default json2list.store.json)",
+			"  -serve <address>          Serve the current wordlist via HTTP (e.g. 127.0.0.1:8000)",
 			"",
 		}
 
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestCheckForInclusion(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"", false},
+		{"a", false},
+		{"id", true},
+		{"translation_key", true},
+		{"APP_STORE_ICON_TOOLTIP", true},
+		{"42", false},
+		{"3.14", false},
+		{"-7", false},
+		{"1e10", false},
+		{"Neue Zeitbuchung", false},
+		{"pentestsecinto/files", false},
+		{"a,b", false},
+		{"{value}", false},
+		{"key:value", false},
+		{"100%", false},
+		{"file.png", false},
+		{"#EF5350", false},
+		{"zurückgezogen", false},
+		{"Zeitänderung", false},
+		{"größe", false},
+		{"ÖBB", false},
+		{"Übersicht", false},
+		{"Ärger", false},
+		{"2020-06-12", false},
+		{"0000-00-00", false},
+		{"1-2-3", false},
+		{"ext-5", true},
+		{"time-tracking", true},
+		{"--", true},
+	}
+
+	for _, tt := range tests {
+		if got := checkForInclusion(tt.content); got != tt.want {
+			t.Errorf("checkForInclusion(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string
+		want    []string
+		added   []bool
+		present map[string]int
+	}{
+		{
+			name:  "lower case is kept",
+			input: []string{"firstname"},
+			want:  []string{"firstname"},
+			added: []bool{true},
+		},
+		{
+			name:  "mixed case is lowered",
+			input: []string{"iconCls", "Robert"},
+			want:  []string{"iconcls", "robert"},
+			added: []bool{true, true},
+		},
+		{
+			name:  "upper case is preserved",
+			input: []string{"PM_LIST_EXT5", "ADD"},
+			want:  []string{"PM_LIST_EXT5", "ADD"},
+			added: []bool{true, true},
+		},
+		{
+			name:  "duplicates are dropped",
+			input: []string{"Blank", "blank", "BLANK", "blank"},
+			want:  []string{"blank", "BLANK"},
+			added: []bool{true, false, true, false},
+		},
+		{
+			name:    "already known entries are dropped",
+			input:   []string{"id", "name"},
+			want:    []string{"name"},
+			added:   []bool{false, true},
+			present: map[string]int{"id": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []string
+			counts := make(map[string]int)
+			for k, v := range tt.present {
+				counts[k] = v
+			}
+			for i, entry := range tt.input {
+				if got := add(entry, &entries, counts); got != tt.added[i] {
+					t.Errorf("add(%q) = %v, want %v", entry, got, tt.added[i])
+				}
+			}
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("entries = %q, want %q", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "keys and string values",
+			input: `{"translation_key": "abgelehnt", "value": "Neue Zeitbuchung"}`,
+			want:  []string{"translation_key", "abgelehnt", "value"},
+		},
+		{
+			name:  "numbers, booleans and null are skipped",
+			input: `{"id": "37", "leaf": true, "count": 3, "parent": null}`,
+			want:  []string{"id"},
+		},
+		{
+			name:  "nested objects and arrays",
+			input: `{"navigation": {"results": {"children": [{"id": "timetracking"}]}}}`,
+			want:  []string{"navigation", "results", "children", "id", "timetracking"},
+		},
+		{
+			name:  "keys of excluded values are used",
+			input: `{"calendar_color": "#EF5350", "exit_date": "0000-00-00"}`,
+			want:  []string{"calendar_color", "exit_date"},
+		},
+		{
+			name:  "excluded keys",
+			input: `{"1": "one", "a b": "two", "x": {"y.z": []}}`,
+			want:  []string{"one", "two"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aMap, err := decodeOrdered([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var entries []string
+			parseMap(aMap.(*object), &entries, make(map[string]int))
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("entries = %q, want %q", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArray(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "string values",
+			input: `["calendar", "calendar_list", "Neue Zeitbuchung", "42"]`,
+			want:  []string{"calendar", "calendar_list"},
+		},
+		{
+			name:  "nested arrays",
+			input: `[["tree_view"], [["PM_LIST_EXT5"]], null, 1, false]`,
+			want:  []string{"tree_view", "PM_LIST_EXT5"},
+		},
+		{
+			name:  "objects in arrays",
+			input: `[{"text": "PM_MENUBOX_CALENDARVIEW"}, {"text": "PM_LIST_EXT5"}]`,
+			want:  []string{"text", "PM_MENUBOX_CALENDARVIEW", "PM_LIST_EXT5"},
+		},
+		{
+			name:  "empty",
+			input: `[]`,
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anArray, err := decodeOrdered([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var entries []string
+			parseArray(anArray.([]interface{}), &entries, make(map[string]int))
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("entries = %q, want %q", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateWordListGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, _, err := createWordList(input, nil, orderSource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareWithFile(t, entries, filepath.Join("testdata", name+".golden"))
+		})
+	}
+}
+
+// TestBundledWordList checks that the bundled input.json still creates the
+// bundled wordlist.txt.
+func TestBundledWordList(t *testing.T) {
+	input, err := os.ReadFile("input.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := createWordList(input, nil, orderSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareWithFile(t, entries, "wordlist.txt")
+}
+
+func TestCreateWordListErrors(t *testing.T) {
+	for _, input := range []string{"", "nope", `{"a": `, `{"a": "b"}}`} {
+		if _, _, err := createWordList([]byte(input), nil, orderSource); err == nil {
+			t.Errorf("createWordList(%q) returned no error", input)
+		}
+	}
+}
+
+// compareWithFile compares the entries with the lines of the file. With
+// -update the file is rewritten with the entries before.
+func compareWithFile(t *testing.T, entries []string, file string) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(file, []byte(strings.Join(entries, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("%s: got %d entries, want %d", file, len(entries), len(want))
+		for i := 0; i < len(entries) && i < len(want); i++ {
+			if entries[i] != want[i] {
+				t.Errorf("first difference in line %d: got %q, want %q", i+1, entries[i], want[i])
+				break
+			}
+		}
+	}
+}
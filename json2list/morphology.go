@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// variant is a word derived from an existing wordlist entry together with the
+// rule which produced it (e.g. "de-stem" or "en-plural").
+type variant struct {
+	word string
+	rule string
+}
+
+// morphology holds the settings of the optional morphology stage. A nil
+// morphology disables the stage.
+type morphology struct {
+	languages  []string
+	rules      map[string]bool
+	dictionary map[string]bool
+}
+
+const minPartLength = 3
+
+var germanFugen = []string{"es", "en", "s", "n", "e"}
+
+// germanStrongParticiples maps the past participles of common strong verbs,
+// without ge- and separable prefix, to their stem vowel changed infinitive.
+// Participles which keep the vowel of the infinitive (gegeben -> geben) don't
+// need an entry.
+var germanStrongParticiples = map[string]string{
+	"nommen":    "nehmen",
+	"gangen":    "gehen",
+	"standen":   "stehen",
+	"sprochen":  "sprechen",
+	"brochen":   "brechen",
+	"troffen":   "treffen",
+	"holfen":    "helfen",
+	"worfen":    "werfen",
+	"storben":   "sterben",
+	"funden":    "finden",
+	"bunden":    "binden",
+	"zogen":     "ziehen",
+	"flogen":    "fliegen",
+	"schlossen": "schliessen",
+	"schrieben": "schreiben",
+	"blieben":   "bleiben",
+	"trieben":   "treiben",
+	"wiesen":    "weisen",
+	"griffen":   "greifen",
+	"schnitten": "schneiden",
+	"legen":     "liegen",
+	"sessen":    "sitzen",
+	"wonnen":    "winnen",
+	"sungen":    "singen",
+	"trunken":   "trinken",
+}
+
+// germanSeparablePrefixes are the separable verb prefixes placed before the
+// ge- of the past participle (abgelehnt).
+var germanSeparablePrefixes = []string{"", "ab", "an", "auf", "aus", "bei", "ein", "mit", "nach", "vor", "weg", "zu"}
+
+// Suffixes and letter groups which are typical for one of the languages. They
+// are used to decide which rules are applied to an entry.
+var (
+	germanSuffixes  = []string{"ung", "ungen", "heit", "heiten", "keit", "keiten", "schaft", "schaften", "lich", "isch", "chen", "ieren", "iert", "nis", "innen"}
+	englishSuffixes = []string{"ing", "ings", "ed", "ness", "ment", "ments", "ly", "ful", "less", "able", "ies", "ity", "ous", "ize"}
+	germanLetters   = []string{"sch", "tz", "ei", "pf", "ck"}
+	englishLetters  = []string{"th", "wh", "ee", "oo", "ea", "ou", "oa", "ay", "ey", "ow", "aw", "igh", "ce", "ci"}
+)
+
+// germanNonParticiples are nouns which look like past participles because
+// they start with ge- (gehalt would become halen).
+var germanNonParticiples = map[string]bool{
+	"gebiet":   true,
+	"gebet":    true,
+	"geburt":   true,
+	"gedicht":  true,
+	"gehalt":   true,
+	"gericht":  true,
+	"gesamt":   true,
+	"gesicht":  true,
+	"gestalt":  true,
+	"gewalt":   true,
+	"gewicht":  true,
+	"gespenst": true,
+}
+
+// germanNounSuffixes end nouns which would otherwise be taken for weak
+// participles (gesellschaft, gelegenheit).
+var germanNounSuffixes = []string{"heit", "keit", "schaft"}
+
+// englishIngNouns are -ing forms which are used as countable nouns. Other
+// -ing forms (running, pending) get no plural.
+var englishIngNouns = map[string]bool{
+	"binding":   true,
+	"booking":   true,
+	"building":  true,
+	"ceiling":   true,
+	"drawing":   true,
+	"ending":    true,
+	"finding":   true,
+	"heading":   true,
+	"listing":   true,
+	"mapping":   true,
+	"meeting":   true,
+	"offering":  true,
+	"opening":   true,
+	"painting":  true,
+	"posting":   true,
+	"rating":    true,
+	"reading":   true,
+	"recording": true,
+	"saving":    true,
+	"setting":   true,
+	"warning":   true,
+}
+
+var englishIrregulars = map[string]string{
+	"person": "people",
+	"child":  "children",
+	"man":    "men",
+	"woman":  "women",
+	"mouse":  "mice",
+	"index":  "indices",
+	"datum":  "data",
+}
+
+func newMorphology(languages string, rules string, dictFile string) *morphology {
+	m := &morphology{
+		languages:  splitLanguages(languages),
+		rules:      splitList(rules),
+		dictionary: make(map[string]bool),
+	}
+	if dictFile != "" {
+		file, err := os.Open(dictFile)
+		if err != nil {
+			fmt.Println(err)
+			return m
+		}
+		defer file.Close()
+
+		sc := bufio.NewScanner(file)
+		for sc.Scan() {
+			word := strings.ToLower(strings.TrimSpace(sc.Text()))
+			if isWord(word) {
+				m.dictionary[word] = true
+			}
+		}
+	}
+	return m
+}
+
+// learn adds the entries and the parts of snake case or dashed entries to the
+// dictionary used for the compound splitting.
+func (m *morphology) learn(entries []string) {
+	for _, entry := range entries {
+		parts := strings.FieldsFunc(strings.ToLower(entry), func(r rune) bool {
+			return r == '_' || r == '-'
+		})
+		for _, part := range parts {
+			if isWord(part) && len(part) >= minPartLength {
+				m.dictionary[part] = true
+			}
+		}
+	}
+}
+
+// variants creates the morphological variants for all entries which are plain
+// words. The entries are learned as dictionary before.
+func (m *morphology) variants(entries []string) []variant {
+	m.learn(entries)
+
+	var result []variant
+	for _, entry := range entries {
+		if !isWord(entry) {
+			continue
+		}
+		switch m.language(entry) {
+		case "en":
+			m.emit(&result, "en-stem", stemEnglish(entry))
+			for _, word := range pluralEnglish(entry) {
+				m.emit(&result, "en-plural", word)
+			}
+			m.emit(&result, "en-singular", singularEnglish(entry))
+		case "de":
+			// Participles are stemmed by their infinitive, their own stem
+			// (angenomm) is no German word.
+			if infinitive := infinitiveGerman(entry); infinitive != "" {
+				m.emit(&result, "de-infinitive", infinitive)
+				m.emit(&result, "de-stem", stemGerman(infinitive))
+				continue
+			}
+			m.emit(&result, "de-stem", stemGerman(entry))
+			for _, word := range pluralGerman(entry) {
+				m.emit(&result, "de-plural", word)
+			}
+			m.emit(&result, "de-singular", singularGerman(entry))
+			if len(m.rules) == 0 || m.rules["de-compound"] {
+				for _, part := range m.splitCompound(entry) {
+					m.emit(&result, "de-compound", part)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// language returns the language whose rules are applied to the word. If more
+// than one language is enabled the typical suffixes and letter groups of the
+// word decide, the first language given wins a tie.
+func (m *morphology) language(word string) string {
+	if len(m.languages) < 2 {
+		if len(m.languages) == 0 {
+			return ""
+		}
+		return m.languages[0]
+	}
+	german, english := languageCues(word)
+	for _, language := range m.languages {
+		if language == "de" && german > english || language == "en" && english > german {
+			return language
+		}
+	}
+	return m.languages[0]
+}
+
+// languageCues counts the German and the English cues of the word. Suffixes
+// and past participles count twice, letter groups once.
+func languageCues(word string) (int, int) {
+	var german, english int
+	if infinitiveGerman(word) != "" {
+		german += 2
+	}
+	for _, suffix := range germanSuffixes {
+		if strings.HasSuffix(word, suffix) {
+			german += 2
+			break
+		}
+	}
+	for _, suffix := range englishSuffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= minPartLength {
+			english += 2
+			break
+		}
+	}
+	for _, letters := range germanLetters {
+		if strings.Contains(word, letters) {
+			german++
+		}
+	}
+	for _, letters := range englishLetters {
+		if strings.Contains(word, letters) {
+			english++
+		}
+	}
+	return german, english
+}
+
+func (m *morphology) emit(result *[]variant, rule string, word string) {
+	if len(word) < 2 {
+		return
+	}
+	if len(m.rules) > 0 && !m.rules[rule] {
+		return
+	}
+	*result = append(*result, variant{word: word, rule: rule})
+}
+
+// splitCompound splits a German compound noun into known parts, also allowing
+// the usual linking elements (Fugenelemente) between them. If the word can't
+// be split completely no parts are returned.
+func (m *morphology) splitCompound(word string) []string {
+	if len(word) < 2*minPartLength {
+		return nil
+	}
+	for i := len(word) - minPartLength; i >= minPartLength; i-- {
+		head := word[:i]
+		tail := word[i:]
+
+		head = m.knownHead(head)
+		if head == "" {
+			continue
+		}
+		if m.dictionary[tail] {
+			return []string{head, tail}
+		}
+		if parts := m.splitCompound(tail); parts != nil {
+			return append([]string{head}, parts...)
+		}
+	}
+	return nil
+}
+
+func (m *morphology) knownHead(head string) string {
+	if m.dictionary[head] {
+		return head
+	}
+	for _, fuge := range germanFugen {
+		stripped := strings.TrimSuffix(head, fuge)
+		if stripped != head && len(stripped) >= minPartLength && m.dictionary[stripped] {
+			return stripped
+		}
+	}
+	return ""
+}
+
+func stemEnglish(word string) string {
+	stem := word
+	switch {
+	case strings.HasSuffix(stem, "sses"):
+		stem = strings.TrimSuffix(stem, "es")
+	case strings.HasSuffix(stem, "ies"):
+		stem = strings.TrimSuffix(stem, "ies") + "y"
+	case strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "ss") &&
+		!strings.HasSuffix(stem, "us") && !strings.HasSuffix(stem, "is"):
+		stem = strings.TrimSuffix(stem, "s")
+	}
+
+	for _, suffix := range []string{"ing", "ed"} {
+		base := strings.TrimSuffix(stem, suffix)
+		if base != stem && len(base) >= minPartLength && containsVowel(base) {
+			stem = base
+			if strings.HasSuffix(stem, "at") || strings.HasSuffix(stem, "bl") || strings.HasSuffix(stem, "iz") {
+				stem += "e"
+			} else if hasDoubleConsonant(stem) && !strings.ContainsAny(stem[len(stem)-1:], "lsz") {
+				stem = stem[:len(stem)-1]
+			}
+			break
+		}
+	}
+
+	for _, suffix := range []string{"ational", "ization", "fulness", "iveness", "ousness",
+		"ation", "ment", "ness", "able", "ible", "less", "ful", "ly"} {
+		base := strings.TrimSuffix(stem, suffix)
+		if base != stem && len(base) >= minPartLength {
+			switch suffix {
+			case "ational", "ation":
+				base += "ate"
+			case "ization":
+				base += "ize"
+			}
+			stem = base
+			break
+		}
+	}
+
+	if stem == word {
+		return ""
+	}
+	return stem
+}
+
+func pluralEnglish(word string) []string {
+	if plural, ok := englishIrregulars[word]; ok {
+		return []string{plural}
+	}
+	if looksPluralEnglish(word) || isIngForm(word) && !englishIngNouns[word] {
+		return nil
+	}
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !isVowel(word[len(word)-2]):
+		return []string{strings.TrimSuffix(word, "y") + "ies"}
+	case strings.HasSuffix(word, "s") || strings.HasSuffix(word, "x") || strings.HasSuffix(word, "z") ||
+		strings.HasSuffix(word, "ch") || strings.HasSuffix(word, "sh"):
+		return []string{word + "es"}
+	}
+	return []string{word + "s"}
+}
+
+func singularEnglish(word string) string {
+	for singular, plural := range englishIrregulars {
+		if word == plural {
+			return singular
+		}
+	}
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses") || strings.HasSuffix(word, "xes") || strings.HasSuffix(word, "zes") ||
+		strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case looksPluralEnglish(word):
+		return strings.TrimSuffix(word, "s")
+	}
+	return ""
+}
+
+// isIngForm reports whether the word is the -ing form of a verb. Words like
+// string or thing have no vowel before the -ing.
+func isIngForm(word string) bool {
+	return strings.HasSuffix(word, "ing") && containsVowel(strings.TrimSuffix(word, "ing"))
+}
+
+func looksPluralEnglish(word string) bool {
+	return strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is") && len(word) > minPartLength
+}
+
+// stemGerman is a light version of the Snowball German stemmer. Suffixes are
+// only removed if they are located in the R1 region of the word.
+func stemGerman(word string) string {
+	stem := word
+	r1 := regionOne(stem)
+
+	removed := false
+	for _, suffix := range []string{"ern", "em", "er", "en", "es", "e"} {
+		if strings.HasSuffix(stem, suffix) && len(stem)-len(suffix) >= r1 {
+			stem = strings.TrimSuffix(stem, suffix)
+			removed = true
+			if strings.HasSuffix(stem, "niss") {
+				stem = strings.TrimSuffix(stem, "s")
+			}
+			break
+		}
+	}
+	if !removed && strings.HasSuffix(stem, "s") && len(stem)-1 >= r1 && len(stem) > 1 &&
+		strings.ContainsAny(stem[len(stem)-2:len(stem)-1], "bdfghklmnrt") {
+		stem = strings.TrimSuffix(stem, "s")
+	}
+
+	for _, suffix := range []string{"est", "en", "er"} {
+		if strings.HasSuffix(stem, suffix) && len(stem)-len(suffix) >= r1 {
+			stem = strings.TrimSuffix(stem, suffix)
+			break
+		}
+	}
+
+	for _, suffix := range []string{"heit", "keit", "lich", "isch", "ung", "end", "ig"} {
+		if strings.HasSuffix(stem, suffix) && len(stem)-len(suffix) >= r1 &&
+			len(stem)-len(suffix) >= minPartLength {
+			stem = strings.TrimSuffix(stem, suffix)
+			break
+		}
+	}
+
+	if stem == word {
+		return ""
+	}
+	return stem
+}
+
+func pluralGerman(word string) []string {
+	switch {
+	case strings.HasSuffix(word, "ungen") || strings.HasSuffix(word, "heiten") ||
+		strings.HasSuffix(word, "keiten") || strings.HasSuffix(word, "innen"):
+		return nil
+	case strings.HasSuffix(word, "ung") || strings.HasSuffix(word, "heit") || strings.HasSuffix(word, "keit") ||
+		strings.HasSuffix(word, "schaft") || strings.HasSuffix(word, "ion"):
+		return []string{word + "en"}
+	case strings.HasSuffix(word, "in"):
+		return []string{word + "nen"}
+	case strings.HasSuffix(word, "e"):
+		return []string{word + "n"}
+	case strings.HasSuffix(word, "er") || strings.HasSuffix(word, "el") || strings.HasSuffix(word, "en") ||
+		strings.HasSuffix(word, "s") || strings.HasSuffix(word, "x") || strings.HasSuffix(word, "z"):
+		return nil
+	case isVowel(word[len(word)-1]):
+		return []string{word + "s"}
+	}
+	return []string{word + "e", word + "en"}
+}
+
+func singularGerman(word string) string {
+	for _, suffix := range []string{"ungen", "heiten", "keiten", "schaften", "ionen"} {
+		if strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, "en")
+		}
+	}
+	if strings.HasSuffix(word, "innen") {
+		return strings.TrimSuffix(word, "nen")
+	}
+	return ""
+}
+
+// infinitiveGerman creates the infinitive of past participles, optionally with
+// a separable prefix. Weak participles end with -t (abgelehnt -> ablehnen,
+// gespeichert -> speichern), strong ones with -en (angenommen -> annehmen).
+func infinitiveGerman(word string) string {
+	if germanNonParticiples[word] {
+		return ""
+	}
+	for _, suffix := range germanNounSuffixes {
+		if strings.HasSuffix(word, suffix) {
+			return ""
+		}
+	}
+	for _, prefix := range germanSeparablePrefixes {
+		rest := strings.TrimPrefix(word, prefix+"ge")
+		if rest == word {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(rest, "t") && len(rest) >= minPartLength+1:
+			stem := strings.TrimSuffix(rest, "t")
+			if strings.HasSuffix(stem, "er") || strings.HasSuffix(stem, "el") {
+				return prefix + stem + "n"
+			}
+			stem = strings.TrimSuffix(stem, "e")
+			if containsVowel(stem) {
+				return prefix + stem + "en"
+			}
+		case strings.HasSuffix(rest, "en") && len(rest) >= minPartLength+2:
+			if infinitive, ok := germanStrongParticiples[rest]; ok {
+				return prefix + infinitive
+			}
+			if containsVowel(strings.TrimSuffix(rest, "en")) {
+				return prefix + rest
+			}
+		}
+	}
+	return ""
+}
+
+// regionOne returns the start of the Snowball R1 region, the region after the
+// first non-vowel following a vowel, but at least after the third letter.
+func regionOne(word string) int {
+	for i := 1; i < len(word); i++ {
+		if !isVowel(word[i]) && isVowel(word[i-1]) {
+			if i+1 < minPartLength {
+				return minPartLength
+			}
+			return i + 1
+		}
+	}
+	return len(word)
+}
+
+func isWord(entry string) bool {
+	if entry == "" {
+		return false
+	}
+	for _, r := range entry {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiouy", c) >= 0
+}
+
+func containsVowel(s string) bool {
+	return strings.ContainsAny(s, "aeiouy")
+}
+
+func hasDoubleConsonant(s string) bool {
+	n := len(s)
+	return n > 1 && s[n-1] == s[n-2] && !isVowel(s[n-1])
+}
+
+// checkLanguages returns an error if the comma separated list contains a
+// language without morphology rules or no language at all.
+func checkLanguages(list string) error {
+	languages := splitLanguages(list)
+	if len(languages) == 0 {
+		return fmt.Errorf("no language given for -lang")
+	}
+	for _, language := range languages {
+		if language != "de" && language != "en" {
+			return fmt.Errorf("unknown language %q for -lang, supported are de and en", language)
+		}
+	}
+	return nil
+}
+
+// splitLanguages returns the comma separated languages in the given order.
+func splitLanguages(list string) []string {
+	var languages []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			languages = append(languages, item)
+		}
+	}
+	return languages
+}
+
+func splitList(list string) map[string]bool {
+	result := make(map[string]bool)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result[item] = true
+		}
+	}
+	return result
+}
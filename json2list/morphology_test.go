@@ -0,0 +1,163 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMorphologyRules(t *testing.T) {
+	tests := []struct {
+		rule string
+		fn   func(string) []string
+		word string
+		want []string
+	}{
+		{"en-stem", one(stemEnglish), "running", []string{"run"}},
+		{"en-stem", one(stemEnglish), "users", []string{"user"}},
+		{"en-stem", one(stemEnglish), "classes", []string{"class"}},
+		{"en-stem", one(stemEnglish), "queries", []string{"query"}},
+		{"en-stem", one(stemEnglish), "created", []string{"create"}},
+		{"en-stem", one(stemEnglish), "management", []string{"manage"}},
+		{"en-stem", one(stemEnglish), "organization", []string{"organize"}},
+		{"en-stem", one(stemEnglish), "user", nil},
+		{"en-plural", pluralEnglish, "user", []string{"users"}},
+		{"en-plural", pluralEnglish, "entry", []string{"entries"}},
+		{"en-plural", pluralEnglish, "box", []string{"boxes"}},
+		{"en-plural", pluralEnglish, "person", []string{"people"}},
+		{"en-plural", pluralEnglish, "users", nil},
+		{"en-plural", pluralEnglish, "running", nil},
+		{"en-plural", pluralEnglish, "pending", nil},
+		{"en-plural", pluralEnglish, "setting", []string{"settings"}},
+		{"en-plural", pluralEnglish, "string", []string{"strings"}},
+		{"en-singular", one(singularEnglish), "users", []string{"user"}},
+		{"en-singular", one(singularEnglish), "entries", []string{"entry"}},
+		{"en-singular", one(singularEnglish), "children", []string{"child"}},
+		{"en-singular", one(singularEnglish), "status", nil},
+		{"de-stem", one(stemGerman), "benutzer", []string{"benutz"}},
+		{"de-stem", one(stemGerman), "verwaltung", []string{"verwalt"}},
+		{"de-stem", one(stemGerman), "ablehnen", []string{"ablehn"}},
+		{"de-stem", one(stemGerman), "zeit", nil},
+		{"de-plural", pluralGerman, "rechnung", []string{"rechnungen"}},
+		{"de-plural", pluralGerman, "benutzerin", []string{"benutzerinnen"}},
+		{"de-plural", pluralGerman, "seite", []string{"seiten"}},
+		{"de-plural", pluralGerman, "konto", []string{"kontos"}},
+		{"de-plural", pluralGerman, "zeit", []string{"zeite", "zeiten"}},
+		{"de-plural", pluralGerman, "benutzer", nil},
+		{"de-singular", one(singularGerman), "rechnungen", []string{"rechnung"}},
+		{"de-singular", one(singularGerman), "benutzerinnen", []string{"benutzerin"}},
+		{"de-singular", one(singularGerman), "zeiten", nil},
+		{"de-infinitive", one(infinitiveGerman), "abgelehnt", []string{"ablehnen"}},
+		{"de-infinitive", one(infinitiveGerman), "gespeichert", []string{"speichern"}},
+		{"de-infinitive", one(infinitiveGerman), "gesammelt", []string{"sammeln"}},
+		{"de-infinitive", one(infinitiveGerman), "gearbeitet", []string{"arbeiten"}},
+		{"de-infinitive", one(infinitiveGerman), "angenommen", []string{"annehmen"}},
+		{"de-infinitive", one(infinitiveGerman), "gegeben", []string{"geben"}},
+		{"de-infinitive", one(infinitiveGerman), "geschrieben", []string{"schreiben"}},
+		{"de-infinitive", one(infinitiveGerman), "gestern", nil},
+		{"de-infinitive", one(infinitiveGerman), "gegen", nil},
+		{"de-infinitive", one(infinitiveGerman), "zeit", nil},
+		{"de-infinitive", one(infinitiveGerman), "gehalt", nil},
+		{"de-infinitive", one(infinitiveGerman), "gesellschaft", nil},
+		{"de-infinitive", one(infinitiveGerman), "gelegenheit", nil},
+	}
+	for _, tt := range tests {
+		if got := tt.fn(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s(%s) = %q, want %q", tt.rule, tt.word, got, tt.want)
+		}
+	}
+}
+
+// one wraps a rule creating a single variant, where "" means no variant.
+func one(fn func(string) string) func(string) []string {
+	return func(word string) []string {
+		if variant := fn(word); variant != "" {
+			return []string{variant}
+		}
+		return nil
+	}
+}
+
+func TestSplitCompound(t *testing.T) {
+	m := newMorphology("de", "", "")
+	m.learn([]string{"benutzer", "verwaltung", "zeit", "buchung", "konto"})
+
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"benutzerverwaltung", []string{"benutzer", "verwaltung"}},
+		{"zeitbuchung", []string{"zeit", "buchung"}},
+		{"buchungskonto", []string{"buchung", "konto"}},
+		{"zeitbuchungskonto", []string{"zeit", "buchung", "konto"}},
+		{"benutzerkonten", nil},
+		{"zeit", nil},
+	}
+	for _, tt := range tests {
+		if got := m.splitCompound(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCompound(%s) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		languages string
+		word      string
+		want      string
+	}{
+		{"de,en", "abgelehnt", "de"},
+		{"de,en", "angenommen", "de"},
+		{"de,en", "verwaltung", "de"},
+		{"de,en", "zeit", "de"},
+		{"de,en", "running", "en"},
+		{"de,en", "management", "en"},
+		{"de,en", "through", "en"},
+		{"de,en", "users", "de"},
+		{"en,de", "users", "en"},
+		{"en,de", "zeit", "de"},
+		{"en", "zeit", "en"},
+		{"", "zeit", ""},
+	}
+	for _, tt := range tests {
+		m := newMorphology(tt.languages, "", "")
+		if got := m.language(tt.word); got != tt.want {
+			t.Errorf("language(%s) with %s = %q, want %q", tt.word, tt.languages, got, tt.want)
+		}
+	}
+}
+
+func TestCheckLanguages(t *testing.T) {
+	for _, list := range []string{"de,en", "en", " de , en "} {
+		if err := checkLanguages(list); err != nil {
+			t.Errorf("checkLanguages(%q) = %v, want nil", list, err)
+		}
+	}
+	for _, list := range []string{"", ",", "fr", "de,fr", "DE"} {
+		if err := checkLanguages(list); err == nil {
+			t.Errorf("checkLanguages(%q) returned no error", list)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	m := newMorphology("de,en", "", "")
+	got := m.variants([]string{"abgelehnt", "angenommen", "running", "zeit", "gespeichert", "zeit-buchung", "zeitbuchung"})
+	want := []variant{
+		{"ablehnen", "de-infinitive"}, {"ablehn", "de-stem"},
+		{"annehmen", "de-infinitive"}, {"annehm", "de-stem"},
+		{"run", "en-stem"},
+		{"zeite", "de-plural"}, {"zeiten", "de-plural"},
+		{"speichern", "de-infinitive"}, {"speich", "de-stem"},
+		{"zeitbuch", "de-stem"}, {"zeitbuchungen", "de-plural"}, {"zeit", "de-compound"}, {"buchung", "de-compound"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("variants = %v, want %v", got, want)
+	}
+
+	m = newMorphology("de,en", "de-infinitive,en-stem", "")
+	got = m.variants([]string{"abgelehnt", "running", "zeit"})
+	want = []variant{{"ablehnen", "de-infinitive"}, {"run", "en-stem"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered variants = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	orderSource    = "source"
+	orderAlpha     = "alpha"
+	orderFrequency = "freq"
+	orderLength    = "length"
+)
+
+// object is a decoded JSON object which keeps its keys in document order. If a
+// key is contained more than once the position of the first and the value of
+// the last occurrence is used, same as with json.Unmarshal.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// decodeOrdered decodes the JSON data like json.Unmarshal into an interface{}
+// but uses *object instead of map[string]interface{} for JSON objects.
+func decodeOrdered(buffer []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(buffer))
+
+	value, err := decodeValue(dec)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value at offset %d", dec.InputOffset())
+	}
+	return value, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := &object{values: make(map[string]interface{})}
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid object key at offset %d", dec.InputOffset())
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := obj.values[key]; !exists {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		// Consume the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+
+	case json.Delim('['):
+		array := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+
+	case json.Delim('}'), json.Delim(']'):
+		return nil, fmt.Errorf("unexpected %v at offset %d", token, dec.InputOffset())
+	}
+
+	return token, nil
+}
+
+// sortEntries orders the entries in place. Source order is the order in which
+// the entries have been found in the JSON document. All other orders fall back
+// to the source order for entries which are equal.
+func sortEntries(entries []string, counts map[string]int, order string) error {
+	switch order {
+	case orderSource, "":
+		return nil
+	case orderAlpha:
+		sort.Strings(entries)
+	case orderFrequency:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return counts[entries[i]] > counts[entries[j]]
+		})
+	case orderLength:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return len(entries[i]) < len(entries[j])
+		})
+	default:
+		return fmt.Errorf("unknown sort order %q", order)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeOrdered(t *testing.T) {
+	value, err := decodeOrdered([]byte(`{"z": 1, "a": {"y": [true, null, "x"], "b": 2.5}, "m": "v", "z": "last"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := value.(*object)
+	if want := []string{"z", "a", "m"}; !reflect.DeepEqual(obj.keys, want) {
+		t.Errorf("keys = %q, want %q", obj.keys, want)
+	}
+	if obj.values["z"] != "last" {
+		t.Errorf("duplicate key value = %v, want last", obj.values["z"])
+	}
+
+	nested := obj.values["a"].(*object)
+	if want := []string{"y", "b"}; !reflect.DeepEqual(nested.keys, want) {
+		t.Errorf("nested keys = %q, want %q", nested.keys, want)
+	}
+	if want := []interface{}{true, nil, "x"}; !reflect.DeepEqual(nested.values["y"], want) {
+		t.Errorf("array = %v, want %v", nested.values["y"], want)
+	}
+	if nested.values["b"] != 2.5 {
+		t.Errorf("number = %v, want 2.5", nested.values["b"])
+	}
+}
+
+func TestDecodeOrderedErrors(t *testing.T) {
+	for _, input := range []string{"", " ", "{", "[1,", `{"a" 1}`, `{1: 2}`, `[1] 2`, `}`, `{"a": ]}`} {
+		if _, err := decodeOrdered([]byte(input)); err == nil {
+			t.Errorf("decodeOrdered(%q) returned no error", input)
+		}
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	entries := []string{"results", "id", "ADD", "translation_key", "value", "abgelehnt"}
+	counts := map[string]int{"results": 2, "id": 5, "ADD": 1, "translation_key": 5, "value": 5, "abgelehnt": 1}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{orderSource, []string{"results", "id", "ADD", "translation_key", "value", "abgelehnt"}},
+		{orderAlpha, []string{"ADD", "abgelehnt", "id", "results", "translation_key", "value"}},
+		{orderFrequency, []string{"id", "translation_key", "value", "results", "ADD", "abgelehnt"}},
+		{orderLength, []string{"id", "ADD", "value", "results", "abgelehnt", "translation_key"}},
+	}
+
+	for _, tt := range tests {
+		got := append([]string(nil), entries...)
+		if err := sortEntries(got, counts, tt.order); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.order, got, tt.want)
+		}
+	}
+
+	if err := sortEntries(entries, counts, "random"); err == nil {
+		t.Error("unknown order returned no error")
+	}
+}
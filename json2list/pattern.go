@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFormats are the layouts which are checked for date-like values. Values
+// with a time are profiled by their date only.
+var dateFormats = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"02.01.2006",
+	"20060102",
+}
+
+var seasons = []string{"Spring", "Summer", "Autumn", "Fall", "Winter", "Fruehling", "Sommer", "Herbst"}
+
+var idRegex = regexp.MustCompile(`^([A-Za-z]*[-_]?)([0-9]{1,18})$`)
+
+// idPattern describes the numeric values of a key with an optional prefix
+// and zero padding, e.g. "MA0037" has the prefix "MA" and the width 4.
+type idPattern struct {
+	Prefix  string      `json:"prefix,omitempty"`
+	Width   int         `json:"width,omitempty"`
+	Min     int64       `json:"min"`
+	Max     int64       `json:"max"`
+	Count   int         `json:"count"`
+	Lengths map[int]int `json:"lengths"`
+	values  map[int64]bool
+}
+
+// datePattern describes the date-like values of a key in one format.
+type datePattern struct {
+	Format string `json:"format"`
+	First  string `json:"first"`
+	Last   string `json:"last"`
+	Count  int    `json:"count"`
+	dates  map[string]time.Time
+}
+
+// keyProfile contains the numeric and date-like values observed for a key.
+type keyProfile struct {
+	Key   string         `json:"key"`
+	IDs   []*idPattern   `json:"ids,omitempty"`
+	Dates []*datePattern `json:"dates,omitempty"`
+	Years []int          `json:"years,omitempty"`
+}
+
+// valueProfile contains the profiles of all keys in document order.
+type valueProfile struct {
+	keys     []string
+	profiles map[string]*keyProfile
+}
+
+func newValueProfile() *valueProfile {
+	return &valueProfile{profiles: make(map[string]*keyProfile)}
+}
+
+// profileValue walks the decoded JSON data and profiles all numeric and
+// date-like values. Values in arrays are profiled with the key of the array.
+func (p *valueProfile) profileValue(key string, val interface{}) {
+	switch concreteVal := val.(type) {
+	case *object:
+		for _, k := range concreteVal.keys {
+			p.profileValue(k, concreteVal.values[k])
+		}
+	case []interface{}:
+		for _, v := range concreteVal {
+			p.profileValue(key, v)
+		}
+	case float64:
+		if concreteVal == math.Trunc(concreteVal) && math.Abs(concreteVal) < 1e18 {
+			p.addID(key, "", 0, int64(concreteVal), len(strconv.FormatInt(int64(concreteVal), 10)))
+		}
+	case string:
+		p.profileString(key, concreteVal)
+	}
+}
+
+func (p *valueProfile) profileString(key string, value string) {
+	for _, format := range dateFormats {
+		if date, err := time.Parse(format, value); err == nil {
+			p.addDate(key, format, date)
+			return
+		}
+	}
+
+	match := idRegex.FindStringSubmatch(value)
+	if match == nil {
+		return
+	}
+	number, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return
+	}
+	width := 0
+	if len(match[2]) > 1 && match[2][0] == '0' {
+		width = len(match[2])
+	}
+	p.addID(key, match[1], width, number, len(match[2]))
+}
+
+func (p *valueProfile) profile(key string) *keyProfile {
+	kp, ok := p.profiles[key]
+	if !ok {
+		kp = &keyProfile{Key: key}
+		p.profiles[key] = kp
+		p.keys = append(p.keys, key)
+	}
+	return kp
+}
+
+func (p *valueProfile) addID(key string, prefix string, width int, number int64, length int) {
+	kp := p.profile(key)
+
+	var pattern *idPattern
+	for _, existing := range kp.IDs {
+		if existing.Prefix == prefix && existing.Width == width {
+			pattern = existing
+			break
+		}
+	}
+	if pattern == nil {
+		pattern = &idPattern{Prefix: prefix, Width: width, Min: number, Max: number,
+			Lengths: make(map[int]int), values: make(map[int64]bool)}
+		kp.IDs = append(kp.IDs, pattern)
+	}
+
+	pattern.Count++
+	pattern.Lengths[length]++
+	pattern.values[number] = true
+	if number < pattern.Min {
+		pattern.Min = number
+	}
+	if number > pattern.Max {
+		pattern.Max = number
+	}
+
+	lowerKey := strings.ToLower(key)
+	if prefix == "" && number >= 1900 && number <= 2100 &&
+		(strings.Contains(lowerKey, "year") || strings.Contains(lowerKey, "jahr")) {
+		kp.addYear(int(number))
+	}
+}
+
+func (p *valueProfile) addDate(key string, format string, date time.Time) {
+	kp := p.profile(key)
+
+	var pattern *datePattern
+	for _, existing := range kp.Dates {
+		if existing.Format == format {
+			pattern = existing
+			break
+		}
+	}
+	if pattern == nil {
+		pattern = &datePattern{Format: format, dates: make(map[string]time.Time)}
+		kp.Dates = append(kp.Dates, pattern)
+	}
+
+	day := date.Format("2006-01-02")
+	pattern.Count++
+	pattern.dates[day] = date
+	if pattern.First == "" || day < pattern.First {
+		pattern.First = day
+	}
+	if day > pattern.Last {
+		pattern.Last = day
+	}
+	kp.addYear(date.Year())
+}
+
+func (kp *keyProfile) addYear(year int) {
+	for _, existing := range kp.Years {
+		if existing == year {
+			return
+		}
+	}
+	kp.Years = append(kp.Years, year)
+	sort.Ints(kp.Years)
+}
+
+// isFlag reports whether the pattern only contains the values 0 and 1, which
+// are used as booleans and aren't worth generating candidates for.
+func (pattern *idPattern) isFlag() bool {
+	return pattern.Prefix == "" && pattern.Min >= 0 && pattern.Max <= 1
+}
+
+func (pattern *idPattern) format(number int64) string {
+	return fmt.Sprintf("%s%0*d", pattern.Prefix, pattern.Width, number)
+}
+
+// candidates generates the candidate values from the profile: neighbouring
+// IDs and dates, the years around the observed ones and Season+Year
+// passwords. Each candidate is tagged with the kind of pattern it is based on.
+func (p *valueProfile) candidates(neighbours int, yearSpan int) ([]string, map[string]string) {
+	var entries []string
+	tags := make(map[string]string)
+	addCandidate := func(candidate string, tag string) {
+		if _, exists := tags[candidate]; !exists {
+			entries = append(entries, candidate)
+			tags[candidate] = tag
+		}
+	}
+
+	years := make(map[int]bool)
+	for _, key := range p.keys {
+		kp := p.profiles[key]
+
+		for _, pattern := range kp.IDs {
+			if pattern.isFlag() {
+				continue
+			}
+			for _, number := range sortedNumbers(pattern.values) {
+				for n := number - int64(neighbours); n <= number+int64(neighbours); n++ {
+					if n >= 0 {
+						addCandidate(pattern.format(n), "id")
+					}
+				}
+			}
+		}
+
+		for _, pattern := range kp.Dates {
+			for _, day := range sortedDays(pattern.dates) {
+				date := pattern.dates[day]
+				for n := -neighbours; n <= neighbours; n++ {
+					addCandidate(date.AddDate(0, 0, n).Format(pattern.Format), "date")
+				}
+			}
+		}
+
+		for _, year := range kp.Years {
+			for y := year - yearSpan; y <= year+yearSpan; y++ {
+				years[y] = true
+			}
+		}
+	}
+
+	var sortedYears []int
+	for year := range years {
+		sortedYears = append(sortedYears, year)
+	}
+	sort.Ints(sortedYears)
+
+	for _, year := range sortedYears {
+		addCandidate(strconv.Itoa(year), "year")
+	}
+	for _, year := range sortedYears {
+		for _, season := range seasons {
+			for _, candidate := range []string{
+				fmt.Sprintf("%s%d", season, year),
+				fmt.Sprintf("%s%d!", season, year),
+				fmt.Sprintf("%s%02d", season, year%100),
+				fmt.Sprintf("%s%d", strings.ToLower(season), year),
+			} {
+				addCandidate(candidate, "season-year")
+			}
+		}
+	}
+	return entries, tags
+}
+
+// checkPatternFiles returns an error if the candidate or profile file is the
+// wordlist output file, which is written in pattern mode as well.
+func checkPatternFiles(outputFile string, candidateFile string, profileFile string) error {
+	output, err := filepath.Abs(outputFile)
+	if err != nil {
+		return err
+	}
+	for _, f := range []struct{ flag, name string }{{"-candidates", candidateFile}, {"-profile", profileFile}} {
+		if f.name == "" {
+			continue
+		}
+		if name, err := filepath.Abs(f.name); err == nil && name == output {
+			return fmt.Errorf("%s %s would overwrite the wordlist written to -o", f.flag, f.name)
+		}
+	}
+	return nil
+}
+
+// writePatterns profiles the JSON data and writes the generated candidates to
+// the candidate file. If a profile file is given the profile is written to it
+// as JSON.
+func writePatterns(buffer []byte, candidateFile string, profileFile string, neighbours int, yearSpan int, tagged bool) error {
+	result, err := decodeOrdered(buffer)
+	if err != nil {
+		return err
+	}
+
+	p := newValueProfile()
+	p.profileValue("", result)
+
+	if profileFile != "" {
+		var profiles []*keyProfile
+		for _, key := range p.keys {
+			profiles = append(profiles, p.profiles[key])
+		}
+		content, err := json.MarshalIndent(profiles, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(profileFile, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	entries, tags := p.candidates(neighbours, yearSpan)
+
+	file, err := os.Create(candidateFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeWordList(file, entries, tags, tagged)
+}
+
+func sortedNumbers(values map[int64]bool) []int64 {
+	var numbers []int64
+	for number := range values {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+func sortedDays(dates map[string]time.Time) []string {
+	var days []string
+	for day := range dates {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
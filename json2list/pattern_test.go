@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValueProfile(t *testing.T) {
+	input := `{
+		"users": [
+			{"id": "37", "personnel_number": "MA0037", "active": "1", "exit_date": "0000-00-00",
+				"payroll_accounting_starts_at": "2020-06-12"},
+			{"id": 12, "personnel_number": "MA0012", "active": "0", "data_changed": "2021-03-01 11:22:24"}
+		],
+		"fiscal_year": 2019,
+		"name": "Blank Robert"
+	}`
+	value, err := decodeOrdered([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newValueProfile()
+	p.profileValue("", value)
+
+	if want := []string{"id", "personnel_number", "active", "payroll_accounting_starts_at", "data_changed", "fiscal_year"}; !reflect.DeepEqual(p.keys, want) {
+		t.Errorf("keys = %q, want %q", p.keys, want)
+	}
+
+	id := p.profiles["id"].IDs[0]
+	if id.Min != 12 || id.Max != 37 || id.Count != 2 || id.Width != 0 || !reflect.DeepEqual(id.Lengths, map[int]int{2: 2}) {
+		t.Errorf("id pattern = %+v", id)
+	}
+
+	personnel := p.profiles["personnel_number"].IDs[0]
+	if personnel.Prefix != "MA" || personnel.Width != 4 || personnel.Min != 12 || personnel.Max != 37 {
+		t.Errorf("personnel_number pattern = %+v", personnel)
+	}
+	if got := personnel.format(38); got != "MA0038" {
+		t.Errorf("format(38) = %q, want MA0038", got)
+	}
+
+	if !p.profiles["active"].IDs[0].isFlag() {
+		t.Error("active should be detected as flag")
+	}
+
+	dates := p.profiles["data_changed"].Dates[0]
+	if dates.Format != "2006-01-02 15:04:05" || dates.First != "2021-03-01" || dates.Count != 1 {
+		t.Errorf("data_changed pattern = %+v", dates)
+	}
+
+	if got := p.profiles["fiscal_year"].Years; !reflect.DeepEqual(got, []int{2019}) {
+		t.Errorf("fiscal_year years = %v, want [2019]", got)
+	}
+}
+
+func TestValueProfileCandidates(t *testing.T) {
+	value, err := decodeOrdered([]byte(`[{"id": "3", "number": "MA0037", "active": 1, "created": "2021-03-01"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newValueProfile()
+	p.profileValue("", value)
+
+	entries, tags := p.candidates(1, 1)
+
+	for candidate, tag := range map[string]string{
+		"2":             "id",
+		"4":             "id",
+		"MA0036":        "id",
+		"MA0038":        "id",
+		"2021-02-28":    "date",
+		"2021-03-02":    "date",
+		"2020":          "year",
+		"2022":          "year",
+		"Summer2021":    "season-year",
+		"Winter2022!":   "season-year",
+		"Herbst20":      "season-year",
+		"fruehling2020": "season-year",
+	} {
+		if tags[candidate] != tag {
+			t.Errorf("candidate %q tagged %q, want %q", candidate, tags[candidate], tag)
+		}
+	}
+
+	for _, unwanted := range []string{"0", "5", "MA0035", "2019", "2023", "2021-02-27"} {
+		if _, ok := tags[unwanted]; ok {
+			t.Errorf("unexpected candidate %q", unwanted)
+		}
+	}
+
+	if len(entries) != len(tags) {
+		t.Errorf("%d entries but %d tags", len(entries), len(tags))
+	}
+}
+
+func TestCheckPatternFiles(t *testing.T) {
+	tests := []struct {
+		candidates string
+		profile    string
+		valid      bool
+	}{
+		{"ids.txt", "profile.json", true},
+		{"", "", true},
+		{"wordlist.txt", "", false},
+		{"./wordlist.txt", "", false},
+		{"", "wordlist.txt", false},
+	}
+	for _, tt := range tests {
+		err := checkPatternFiles("wordlist.txt", tt.candidates, tt.profile)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("checkPatternFiles(%q, %q) = %v, want valid %v", tt.candidates, tt.profile, err, tt.valid)
+		}
+	}
+}
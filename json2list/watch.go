@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileState is the state of a harvested file. It is used to detect new and
+// changed files between two polls. Counts is the contribution of the file to
+// the counts of the store, which is replaced if the file is changed.
+type fileState struct {
+	Size    int64          `json:"size"`
+	ModTime time.Time      `json:"mod_time"`
+	Hash    string         `json:"hash"`
+	Counts  map[string]int `json:"counts,omitempty"`
+}
+
+// store is the persistent state of the watch mode. It contains the entries
+// including the dedupe state, so that a restarted watcher continues with the
+// existing wordlist instead of harvesting all files again.
+type store struct {
+	Entries []string             `json:"entries"`
+	Counts  map[string]int       `json:"counts"`
+	Tags    map[string]string    `json:"tags"`
+	Files   map[string]fileState `json:"files"`
+}
+
+type watcher struct {
+	dirs       []string
+	pattern    string
+	interval   time.Duration
+	storeFile  string
+	outputFile string
+	morph      *morphology
+	order      string
+	tagged     bool
+
+	mutex    sync.RWMutex
+	store    *store
+	wordlist []byte
+}
+
+func newWatcher(dirs string, pattern string, interval time.Duration, storeFile string, outputFile string,
+	morph *morphology, order string, tagged bool) (*watcher, error) {
+	w := &watcher{
+		pattern:    pattern,
+		interval:   interval,
+		storeFile:  storeFile,
+		outputFile: outputFile,
+		morph:      morph,
+		order:      order,
+		tagged:     tagged,
+	}
+	for _, dir := range strings.Split(dirs, ",") {
+		dir = strings.TrimSpace(dir)
+		if dir != "" {
+			w.dirs = append(w.dirs, dir)
+		}
+	}
+
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid interval %v, it must be positive", interval)
+	}
+	if err := sortEntries(nil, nil, order); err != nil {
+		return nil, err
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	s, err := loadStore(storeFile)
+	if err != nil {
+		return nil, err
+	}
+	w.store = s
+
+	// The dictionary used for splitting compounds isn't persisted, so it is
+	// filled with the already known entries.
+	if morph != nil {
+		morph.learn(s.Entries)
+	}
+
+	return w, w.render()
+}
+
+// run polls the watched directories until the program is stopped. If an
+// address is given the current wordlist is additionally served via HTTP.
+func (w *watcher) run(address string) error {
+	if address != "" {
+		go func() {
+			fmt.Println("Serving wordlist on", address)
+			if err := http.ListenAndServe(address, w); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	for {
+		if _, err := w.poll(); err != nil {
+			fmt.Println(err)
+		}
+		time.Sleep(w.interval)
+	}
+}
+
+// poll harvests all new and changed files in the watched directories and
+// regenerates the wordlist if new entries have been found. It returns the
+// number of harvested files.
+func (w *watcher) poll() (int, error) {
+	// The store and the output file, including their temporary files, are
+	// never harvested, even if they are located in a watched directory.
+	ignored := make(map[string]bool)
+	for _, name := range []string{w.storeFile, w.outputFile} {
+		if abs, err := filepath.Abs(name); err == nil {
+			ignored[abs] = true
+			ignored[abs+tmpSuffix] = true
+		}
+	}
+
+	var files []string
+	for _, dir := range w.dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				// Files might be removed while walking
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			if abs, err := filepath.Abs(path); err == nil && ignored[abs] {
+				return nil
+			}
+			if match, _ := filepath.Match(w.pattern, info.Name()); match {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	sort.Strings(files)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	harvested := 0
+	before := len(w.store.Entries)
+	for _, path := range files {
+		changed, err := w.harvestFile(path)
+		if err != nil {
+			fmt.Println(path, err)
+		}
+		if changed {
+			harvested++
+		}
+	}
+
+	if harvested == 0 {
+		return 0, nil
+	}
+	if len(w.store.Entries) > before {
+		fmt.Printf("Harvested %d files, %d new entries, %d entries in total\n",
+			harvested, len(w.store.Entries)-before, len(w.store.Entries))
+	}
+	if err := w.store.save(w.storeFile); err != nil {
+		return harvested, err
+	}
+	return harvested, w.render()
+}
+
+// harvestFile adds the entries of the file to the store if it is new or has
+// been changed since the last poll. The counts of a changed file replace its
+// previous counts, entries which are no longer contained are kept. Files which
+// can't be parsed are recorded as well and only retried after they have been
+// changed again.
+func (w *watcher) harvestFile(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	state, known := w.store.Files[path]
+	if known && state.Size == info.Size() && state.ModTime.Equal(info.ModTime()) {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	if known && state.Hash == hash {
+		state.Size, state.ModTime = info.Size(), info.ModTime()
+		w.store.Files[path] = state
+		return true, nil
+	}
+
+	var entries []string
+	counts := make(map[string]int)
+	tags := make(map[string]string)
+	if err := harvest(content, &entries, counts, tags, w.morph); err != nil {
+		// The previous counts are kept until the file can be parsed again
+		state.Size, state.ModTime, state.Hash = info.Size(), info.ModTime(), hash
+		w.store.Files[path] = state
+		return true, err
+	}
+
+	for entry, count := range state.Counts {
+		w.store.Counts[entry] -= count
+	}
+	for _, entry := range entries {
+		if _, ok := w.store.Counts[entry]; !ok {
+			w.store.Entries = append(w.store.Entries, entry)
+			if rule, ok := tags[entry]; ok {
+				w.store.Tags[entry] = rule
+			}
+		}
+		w.store.Counts[entry] += counts[entry]
+	}
+	w.store.Files[path] = fileState{Size: info.Size(), ModTime: info.ModTime(), Hash: hash, Counts: counts}
+	return true, nil
+}
+
+// render creates the ordered wordlist from the store and writes it to the
+// output file. The caller must hold the lock.
+func (w *watcher) render() error {
+	entries := append([]string(nil), w.store.Entries...)
+	if err := sortEntries(entries, w.store.Counts, w.order); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := writeWordList(&buf, entries, w.store.Tags, w.tagged); err != nil {
+		return err
+	}
+	w.wordlist = buf.Bytes()
+
+	return writeFileAtomic(w.outputFile, w.wordlist)
+}
+
+// ServeHTTP serves the current wordlist as plain text.
+func (w *watcher) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.mutex.RLock()
+	wordlist := w.wordlist
+	w.mutex.RUnlock()
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Write(wordlist)
+}
+
+func loadStore(storeFile string) (*store, error) {
+	s := &store{}
+	content, err := os.ReadFile(storeFile)
+	if err == nil {
+		if err := json.Unmarshal(content, s); err != nil {
+			return nil, fmt.Errorf("invalid store %s: %v", storeFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if s.Counts == nil {
+		s.Counts = make(map[string]int)
+	}
+	if s.Tags == nil {
+		s.Tags = make(map[string]string)
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]fileState)
+	}
+	return s, nil
+}
+
+func (s *store) save(storeFile string) error {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(storeFile, content)
+}
+
+// tmpSuffix is appended to the name of the temporary file written by
+// writeFileAtomic.
+const tmpSuffix = ".tmp"
+
+// writeFileAtomic writes the content to a temporary file which is renamed
+// afterwards, so readers never see a partially written file.
+func writeFileAtomic(name string, content []byte) error {
+	tmp := name + tmpSuffix
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	dir := t.TempDir()
+	captures := filepath.Join(dir, "captures")
+	if err := os.Mkdir(captures, 0755); err != nil {
+		t.Fatal(err)
+	}
+	storeFile := filepath.Join(dir, "store.json")
+	outputFile := filepath.Join(dir, "wordlist.txt")
+
+	w, err := newWatcher(captures, "*.json", time.Second, storeFile, outputFile, nil, orderSource, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeCapture(t, filepath.Join(captures, "first.json"), `{"translation_key": "abgelehnt"}`)
+	writeCapture(t, filepath.Join(captures, "ignored.txt"), `{"ignored": "ignored"}`)
+	pollAndCheck(t, w, 1, outputFile, "translation_key", "abgelehnt")
+
+	// Unchanged files are not harvested again
+	pollAndCheck(t, w, 0, outputFile, "translation_key", "abgelehnt")
+
+	writeCapture(t, filepath.Join(captures, "second.json"), `[{"value": "angenommen"}, "abgelehnt"]`)
+	pollAndCheck(t, w, 1, outputFile, "translation_key", "abgelehnt", "value", "angenommen")
+
+	// Entries of changed files are added, old entries are kept
+	writeCapture(t, filepath.Join(captures, "first.json"), `{"translation_key": "zurueckgezogen"}`)
+	pollAndCheck(t, w, 1, outputFile, "translation_key", "abgelehnt", "value", "angenommen", "zurueckgezogen")
+
+	// The counts of a changed file replace its previous counts
+	writeCapture(t, filepath.Join(captures, "first.json"), `{"translation_key": "zurueckgezogen", "status": "new"}`)
+	pollAndCheck(t, w, 1, outputFile, "translation_key", "abgelehnt", "value", "angenommen", "zurueckgezogen", "status", "new")
+	for entry, want := range map[string]int{"translation_key": 1, "zurueckgezogen": 1, "abgelehnt": 1} {
+		if got := w.store.Counts[entry]; got != want {
+			t.Errorf("count of %s = %d, want %d", entry, got, want)
+		}
+	}
+
+	// Invalid files are recorded and not retried until they change
+	writeCapture(t, filepath.Join(captures, "partial.json"), `{"incomplete": `)
+	pollAndCheck(t, w, 1, outputFile, "translation_key", "abgelehnt", "value", "angenommen", "zurueckgezogen", "status", "new")
+	pollAndCheck(t, w, 0, outputFile, "translation_key", "abgelehnt", "value", "angenommen", "zurueckgezogen", "status", "new")
+
+	// A restarted watcher continues with the stored state
+	restarted, err := newWatcher(captures, "*.json", time.Second, storeFile, outputFile, nil, orderAlpha, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pollAndCheck(t, restarted, 0, outputFile, "abgelehnt", "angenommen", "new", "status", "translation_key", "value", "zurueckgezogen")
+	if got := restarted.store.Counts["abgelehnt"]; got != 1 {
+		t.Errorf("count of abgelehnt = %d, want 1", got)
+	}
+
+	// Contributions of files changed while the watcher wasn't running are
+	// replaced as well
+	writeCapture(t, filepath.Join(captures, "second.json"), `[{"value": "angenommen"}]`)
+	pollAndCheck(t, restarted, 1, outputFile, "abgelehnt", "angenommen", "new", "status", "translation_key", "value", "zurueckgezogen")
+	if got := restarted.store.Counts["abgelehnt"]; got != 0 {
+		t.Errorf("count of abgelehnt = %d, want 0", got)
+	}
+}
+
+func TestWatcherInvalidInterval(t *testing.T) {
+	dir := t.TempDir()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		_, err := newWatcher(dir, "*.json", interval, filepath.Join(dir, "store"), filepath.Join(dir, "out"), nil, orderSource, false)
+		if err == nil {
+			t.Errorf("interval %v returned no error", interval)
+		}
+	}
+}
+
+func TestWatcherIgnoresOwnFiles(t *testing.T) {
+	dir := t.TempDir()
+	storeFile := filepath.Join(dir, "store.json")
+	outputFile := filepath.Join(dir, "wordlist.json")
+
+	w, err := newWatcher(dir, "*.json", time.Second, storeFile, outputFile, nil, orderSource, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCapture(t, filepath.Join(dir, "capture.json"), `{"key": "value"}`)
+	pollAndCheck(t, w, 1, outputFile, "key", "value")
+	pollAndCheck(t, w, 0, outputFile, "key", "value")
+
+	// Temporary files left over by an interrupted write are ignored too
+	w.pattern = "*"
+	writeCapture(t, outputFile+tmpSuffix, `{"partial": "wordlist"}`)
+	writeCapture(t, storeFile+tmpSuffix, `{"partial": "store"}`)
+	pollAndCheck(t, w, 0, outputFile, "key", "value")
+}
+
+func TestWatcherServeHTTP(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newWatcher(dir, "*.json", time.Second, filepath.Join(dir, "store"), filepath.Join(dir, "out"), nil, orderSource, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCapture(t, filepath.Join(dir, "capture.json"), `{"key": "value"}`)
+	if _, err := w.poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(w)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if want := "key\tjson\nvalue\tjson\n"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	resp, err = server.Client().Post(server.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 405 {
+		t.Errorf("POST status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func writeCapture(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time differs for rewritten files
+	modTime := time.Now().Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pollAndCheck(t *testing.T, w *watcher, harvested int, outputFile string, want ...string) {
+	t.Helper()
+	got, err := w.poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != harvested {
+		t.Errorf("harvested %d files, want %d", got, harvested)
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := strings.Fields(string(content))
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("wordlist = %q, want %q", entries, want)
+	}
+}